@@ -9,15 +9,27 @@ import (
 	"cogentcore.org/core/styles/states"
 	"cogentcore.org/core/styles/units"
 	"cogentcore.org/core/texteditor"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Admiral-Piett/jsonify/app/parse"
 	"os/exec"
+	"sort"
+	"strings"
+	"time"
 )
 
 const CmdEnterKeyChord = "Meta+ReturnEnter"
 const CmdBackspaceKeyChord = "Meta+Backspace"
 const CmdCKeyChord = "Meta+C"
 
+// parseTimeout is how long we'll let a single submit run before giving up on it.
+const parseTimeout = 30 * time.Second
+
+// maxListedRepairs is the most changes we list under the output.  A big mangled paste can need tens of thousands,
+// which nobody's going to read through, and would take a while to show.
+const maxListedRepairs = 100
+
 func main() {
 	b := core.NewBody("JSONify")
 	splits := core.NewSplits(b)
@@ -56,6 +68,10 @@ func main() {
 	outputTextArea := core.NewText(outputTextAreaContainer)
 	outputTextArea.Styler(outputTextAreaStyler)
 
+	// Every change we had to make to the input, so nothing gets fixed behind the user's back.
+	outputChanges := core.NewText(output)
+	outputChanges.Styler(outputChangesStyler)
+
 	outputBtnFrame := core.NewFrame(output)
 	outputBtnFrame.Styler(func(s *styles.Style) {
 		s.Grow.Set(1, 0)
@@ -66,16 +82,58 @@ func main() {
 
 	// --- Event Handlers
 	// TODO - clean this uuuup, do we really need to be so javascripty?
+	// cancelSubmit stops the parse that's running, if there is one, so a new submit doesn't have to wait on it.
+	cancelSubmit := func() {}
 	onSubmit := func(e events.Event) {
-		formattedText, err := parse.Parse(inputEditor.Buffer.String())
-		if err != nil {
-			fmt.Println(err)
-			core.ErrorDialog(b, err, "Unable to parse input:")
-			return
-		}
+		text := inputEditor.Buffer.String()
+		cancelSubmit()
+		ctx, cancel := context.WithTimeout(context.Background(), parseTimeout)
+		cancelSubmit = cancel
 
-		outputTextArea.SetText(formattedText)
-		outputTextArea.Update()
+		outputChanges.SetText("Parsing...")
+		outputChanges.Update()
+
+		// A big or mangled paste can take a while, so we parse off the UI thread, and lock it to show the result.
+		go func() {
+			defer cancel()
+			result, err := (&parse.Parser{}).ParseResultContext(ctx, text)
+			if errors.Is(err, context.Canceled) {
+				// Another submit took over, and it'll show its own result.
+				return
+			}
+			// Working out what changed goes over the whole input, so we do it before we hold up the UI.
+			changes := describeChanges(result.Text, result.Repairs, result.Diagnostics)
+
+			b.AsyncLock()
+			defer b.AsyncUnlock()
+			// A new submit could have come in while we waited on the lock, and we'd write over its result.
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			formattedText := result.Output
+			var docErrs parse.DocumentErrors
+			var truncatedErr *parse.TruncatedError
+			if errors.As(err, &docErrs) && formattedText != "" {
+				// Some of the documents made it through, so show those and let the user know about the rest.
+				fmt.Println(err)
+				core.ErrorSnackbar(b, err, "Some documents were cut off or unable to be parsed")
+			} else if errors.As(err, &truncatedErr) {
+				// We've still got what there was of it, it's just missing the end, so that's what we warn about.
+				fmt.Println(err)
+				core.ErrorSnackbar(b, err, "Input was cut off")
+			} else if err != nil {
+				fmt.Println(err)
+				outputChanges.SetText("")
+				outputChanges.Update()
+				core.ErrorDialog(b, err, "Unable to parse input:")
+				return
+			}
+
+			outputTextArea.SetText(formattedText)
+			outputTextArea.Update()
+			outputChanges.SetText(changes)
+			outputChanges.Update()
+		}()
 	}
 	onClear := func(e events.Event) {
 		inputEditor.Buffer.SetText([]byte(""))
@@ -148,6 +206,52 @@ func outputTextAreaStyler(s *styles.Style) {
 	s.Text.WhiteSpace = styles.WhiteSpacePre
 }
 
+func outputChangesStyler(s *styles.Style) {
+	s.Text.WhiteSpace = styles.WhiteSpacePre
+	s.Color = colors.Scheme.OnSurfaceVariant
+}
+
+// describeChanges lists the changes we made to the input, one per line, along with the line they were on, up to
+// maxListedRepairs of them.  Anywhere the input was cut off comes first, as the output's missing whatever came
+// after it.  The text is what the offsets are into, which isn't the input if we stripped Markdown off it.
+func describeChanges(text string, repairs []parse.Repair, diagnostics []parse.Diagnostic) string {
+	lines := []string{"Changes made:"}
+	// Diagnostics are in the order we came across them, so they're already in the order they're in the text.
+	counter := parse.NewLineCounter(text)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Kind == parse.DiagnosticTruncated {
+			lines = append(lines, fmt.Sprintf("- line %d, %s: %s", counter.Line(diagnostic.Start), diagnostic.Kind,
+				diagnostic.Message))
+		}
+	}
+	if len(repairs) == 0 && len(lines) == 1 {
+		return ""
+	}
+
+	// In the order they're in the text, so we only have to count the lines in it once.
+	sorted := append([]parse.Repair(nil), repairs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	counter = parse.NewLineCounter(text)
+	for _, repair := range sorted[:min(len(sorted), maxListedRepairs)] {
+		// Anything we can't place is counted as on the first line.
+		line := counter.Line(repair.Start)
+		var change string
+		switch {
+		case repair.Original == "":
+			change = fmt.Sprintf("added %s", repair.Replacement)
+		case repair.Replacement == "":
+			change = fmt.Sprintf("removed %s", repair.Original)
+		default:
+			change = fmt.Sprintf("%s to %s", repair.Original, repair.Replacement)
+		}
+		lines = append(lines, fmt.Sprintf("- line %d, %s: %s", line, repair.Kind, change))
+	}
+	if len(sorted) > maxListedRepairs {
+		lines = append(lines, fmt.Sprintf("- and %d more", len(sorted)-maxListedRepairs))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func btnRowStyler(s *styles.Style) {
 	s.Direction = styles.Row
 }
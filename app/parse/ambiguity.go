@@ -0,0 +1,200 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AmbiguityKind is the kind of guess we had to make while repairing the input.
+type AmbiguityKind string
+
+const (
+	// AmbiguityContainer is whether the input as a whole is an object or an array, when the brackets around it (or
+	// the lack of them) don't agree with what's inside.
+	AmbiguityContainer AmbiguityKind = "container"
+	// AmbiguityLineBreak is whether a newline ends a value, or the value carries on onto the next line.
+	AmbiguityLineBreak AmbiguityKind = "line_break"
+)
+
+// Choice is one way of reading something ambiguous, and how likely we think it is.
+type Choice struct {
+	Description string
+	Confidence  float64
+}
+
+// Ambiguity is somewhere in the input that could be read more than one way.
+type Ambiguity struct {
+	Kind AmbiguityKind
+	// Start and End are the byte offsets of what was ambiguous, see `Diagnostic` for what they're offsets into.
+	Start int
+	End   int
+	// Choices is every way we could have read it.  The first is the one we go with, unless told otherwise.
+	Choices []Choice
+	// Chosen is the index of the choice we went with.
+	Chosen int
+	// id tells the ambiguity apart from the others when we parse again with a different choice.
+	id ambiguityID
+}
+
+// ambiguityID is the kind of ambiguity, and where it was in the text being repaired, which starts at the offset.
+type ambiguityID struct {
+	kind          AmbiguityKind
+	offset, start int
+}
+
+func (a Ambiguity) String() string {
+	return fmt.Sprintf("%s at %d: %s", a.Kind, a.Start, a.Choices[a.Chosen].Description)
+}
+
+// Interpretation is one way of reading input that has ambiguities in it.
+type Interpretation struct {
+	Output string
+	// Confidence is how likely this is the right one, out of all the interpretations we came up with.
+	Confidence float64
+	// Ambiguities is the choice this interpretation made for each ambiguity.
+	Ambiguities []Ambiguity
+}
+
+// How we describe the choices, and how likely we think each is.
+const (
+	choiceObject       = "an object"
+	choiceArray        = "an array"
+	choiceEndsValue    = "the newline ends the value"
+	choiceCarriesOn    = "the value carries on onto the next line"
+	endsValueLikely    = 0.8
+	carriesOnLikely    = 0.6
+	bracketsDisagree   = 0.6
+	noBracketsLikely   = 0.8
+	maxInterpretations = 16
+)
+
+// decide records an ambiguity, and returns the index of the choice to go with.  That's the first, unless we're
+// parsing again to see what another choice gives.  Offsets are the same as for `noteRepair`.
+func (s *parseState) decide(kind AmbiguityKind, start, end int, choices []Choice) int {
+	id := ambiguityID{kind: kind, offset: s.repairOffset, start: start}
+	chosen := 0
+	if choice, ok := s.overrides[id]; ok && choice < len(choices) {
+		chosen = choice
+	}
+	s.ambiguities = append(s.ambiguities, Ambiguity{
+		Kind:    kind,
+		Start:   start,
+		End:     end,
+		Choices: choices,
+		Chosen:  chosen,
+		id:      id,
+	})
+	return chosen
+}
+
+// decideContainer double checks `determineObjectType` against the brackets the input had around it, before we
+// trimmed them off.  Offsets are into the input.
+func (s *parseState) decideContainer(untrimmed, trimmed string, isObj bool, start, end int) bool {
+	opening := strings.TrimLeft(untrimmed, `\"'`)
+	bracketed := opening != "" && startsComplexDataStructure(rune(opening[0]))
+
+	var confidence float64
+	switch {
+	case bracketed && (opening[0] == '{') != isObj:
+		// Like `[{"a": 1}, {"a": 2}]`, which is an array, but starts with an object once the `[` is gone.
+		confidence = bracketsDisagree
+	case !bracketed && strings.Contains(trimmed, ":") && strings.Contains(trimmed, ","):
+		confidence = noBracketsLikely
+	default:
+		return isObj
+	}
+
+	describe := map[bool]string{true: choiceObject, false: choiceArray}
+	choices := []Choice{{describe[isObj], confidence}, {describe[!isObj], 1 - confidence}}
+	if s.decide(AmbiguityContainer, start, end, choices) == 1 {
+		return !isObj
+	}
+	return isObj
+}
+
+// decideLineBreak is whether the newline at the index ends the value in `current`.  textAfter is whether the next
+// line has anything on it before its next anchor character, and endsValue is what we'd go with on our own.
+func (s *parseState) decideLineBreak(index int, current string, quoted bool, textAfter, endsValue bool) bool {
+	var choices []Choice
+	if endsValue {
+		// Only bare text could have been meant to carry on, `a: 1` and `a: "x"` are finished.
+		trimmed := strings.TrimSpace(current)
+		if quoted || trimmed == "" || IsNumber(trimmed) || s.literals[trimmed] != "" {
+			return endsValue
+		}
+		choices = []Choice{{choiceEndsValue, endsValueLikely}, {choiceCarriesOn, 1 - endsValueLikely}}
+	} else {
+		if !textAfter {
+			return endsValue
+		}
+		choices = []Choice{{choiceCarriesOn, carriesOnLikely}, {choiceEndsValue, 1 - carriesOnLikely}}
+	}
+
+	if s.decide(AmbiguityLineBreak, index, index+1, choices) == 1 {
+		return !endsValue
+	}
+	return endsValue
+}
+
+// Interpretations parses the input the way `Parse` does, and then again for each other choice it could have made
+// where it had to guess.  It returns up to the limit of them, most likely first, with their confidence out of all
+// the ones we came up with.  Input with nothing ambiguous about it has a single interpretation.
+func (p *Parser) Interpretations(input string, limit int) (interpretations []Interpretation, err error) {
+	defer recoverPanic(&err)
+	first := &parseState{Parser: p, input: input}
+	output, firstErr := first.parse()
+
+	var found []Interpretation
+	add := func(s *parseState, output string) {
+		confidence := 1.0
+		for _, ambiguity := range s.ambiguities {
+			confidence *= ambiguity.Choices[ambiguity.Chosen].Confidence
+		}
+		for i := range found {
+			if found[i].Output == output {
+				// Different choices that end up in the same place are the same interpretation.
+				found[i].Confidence += confidence
+				return
+			}
+		}
+		found = append(found, Interpretation{Output: output, Confidence: confidence, Ambiguities: s.ambiguities})
+	}
+	// Input that was cut off still has an output, it's only incomplete.
+	if firstErr == nil || isTruncated(firstErr) {
+		add(first, output)
+	}
+
+	tried := 0
+	for _, ambiguity := range first.ambiguities {
+		for choice := range ambiguity.Choices {
+			if choice == ambiguity.Chosen || tried == maxInterpretations {
+				continue
+			}
+			tried++
+			s := &parseState{Parser: p, input: input, overrides: map[ambiguityID]int{ambiguity.id: choice}}
+			output, err := s.parse()
+			if err == nil || isTruncated(err) {
+				add(s, output)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, firstErr
+	}
+	total := 0.0
+	for _, interpretation := range found {
+		total += interpretation.Confidence
+	}
+	for i := range found {
+		found[i].Confidence /= total
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Confidence > found[j].Confidence
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
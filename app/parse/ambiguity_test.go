@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_ParseResult_success_records_ambiguities(t *testing.T) {
+	input := "a: hello\nworld\nb: 1"
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"a": "helloworld", "b": 1}`, result.Output)
+	assert.Len(t, result.Ambiguities, 2)
+	assert.Equal(t, "line_break at 8: the value carries on onto the next line", result.Ambiguities[0].String())
+	assert.Equal(t, "line_break at 14: the newline ends the value", result.Ambiguities[1].String())
+	assert.Equal(t, []Choice{
+		{Description: choiceCarriesOn, Confidence: carriesOnLikely},
+		{Description: choiceEndsValue, Confidence: 1 - carriesOnLikely},
+	}, result.Ambiguities[0].Choices)
+	assert.Equal(t, "\n", input[result.Ambiguities[0].Start:result.Ambiguities[0].End])
+}
+
+func TestParser_ParseResult_success_nothing_ambiguous(t *testing.T) {
+	for _, input := range []string{`{"a": 1, "b": [1, 2]}`, "a: 1\nb: \"x\"", `[1, 2]`} {
+		result, err := (&Parser{}).ParseResult(input)
+
+		assert.Nil(t, err)
+		assert.Empty(t, result.Ambiguities, input)
+	}
+}
+
+func TestParser_Interpretations_success(t *testing.T) {
+	input := "a: hello\nworld\nb: 1"
+
+	interpretations, err := NewParser(WithCompact()).Interpretations(input, 0)
+
+	assert.Nil(t, err)
+	assert.Len(t, interpretations, 2)
+	assert.Equal(t, `{"a":"helloworld","b":1}`, interpretations[0].Output)
+	assert.Equal(t, `{"a":"hello","worldb":1}`, interpretations[1].Output)
+	assert.Greater(t, interpretations[0].Confidence, interpretations[1].Confidence)
+	assert.InDelta(t, 1, interpretations[0].Confidence+interpretations[1].Confidence, 0.0001)
+	assert.Equal(t, 1, interpretations[1].Ambiguities[0].Chosen)
+
+	interpretations, err = NewParser(WithCompact()).Interpretations(input, 1)
+
+	assert.Nil(t, err)
+	assert.Len(t, interpretations, 1)
+}
+
+func TestParser_Interpretations_success_finds_what_parse_missed(t *testing.T) {
+	input := `[{a: 1}, {a: 2}]`
+
+	_, err := Parse(input)
+	assert.NotNil(t, err)
+
+	interpretations, err := NewParser(WithCompact()).Interpretations(input, 3)
+
+	assert.Nil(t, err)
+	assert.Len(t, interpretations, 1)
+	assert.Equal(t, `[{"a":1},{"a":2}]`, interpretations[0].Output)
+	assert.Equal(t, 1.0, interpretations[0].Confidence)
+	assert.Equal(t, choiceArray, interpretations[0].Ambiguities[0].Choices[1].Description)
+}
+
+func TestParser_Interpretations_failure(t *testing.T) {
+	_, err := (&Parser{}).Interpretations(`a: b: c`, 3)
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,162 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Run with `go test -bench . -benchmem ./app/parse`.  The 100MB payloads take a while, so `-short` skips them.
+var benchmarkSizes = []struct {
+	name string
+	size int
+}{
+	{"1MB", 1 << 20},
+	{"10MB", 10 << 20},
+	{"100MB", 100 << 20},
+}
+
+// benchmarkPayload repeats records made by `record` until there's at least `size` bytes, joined with `separator`.
+func benchmarkPayload(size int, separator string, record func(i int) string) string {
+	payload := strings.Builder{}
+	payload.Grow(size + 1024)
+	for i := 0; payload.Len() < size; i++ {
+		if i > 0 {
+			payload.WriteString(separator)
+		}
+		payload.WriteString(record(i))
+	}
+	return payload.String()
+}
+
+// An API response that's already valid JSON, pretty printed the way it'd be copied out of a browser.
+func validRecord(i int) string {
+	return fmt.Sprintf(`  {
+    "id": %d,
+    "name": "user %d",
+    "email": "user%d@example.com",
+    "active": %t,
+    "score": %d.%d,
+    "tags": ["alpha", "beta", "gamma"],
+    "address": {"street": "%d Main St", "city": "Springfield", "zip": "%05d"}
+  }`, i, i, i, i%2 == 0, i%100, i%10, i, i%100000)
+}
+
+// The same records, keyed by user, as they'd be pasted out of a Python or JavaScript console, which has to be
+// repaired.
+func looseRecord(i int) string {
+	return fmt.Sprintf(`  user_%d: {
+    id: %d,
+    'name': 'user %d',
+    email: user%d@example.com,
+    active: %s,
+    score: %d.%d,
+    tags: ['alpha', 'beta', 'gamma'],
+    address: {street: '%d Main St', city: Springfield, state: 'IL'}
+  }`, i, i, i, i, map[bool]string{true: "True", false: "None"}[i%2 == 0], i%100, i%10, i)
+}
+
+// Log lines, as you'd get out of a multi hundred MB NDJSON export.
+func ndjsonRecord(i int) string {
+	return fmt.Sprintf(`{"ts":"2024-01-01T00:00:%02dZ","level":"info","msg":"request %d done","status":200,`+
+		`"latency_ms":%d,"user":{"id":%d,"roles":["admin","dev"]}}`, i%60, i, i%1000, i)
+}
+
+func benchmarkParse(b *testing.B, payload func(size int) string, options ...Option) {
+	p := NewParser(options...)
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			if testing.Short() && size.size > 10<<20 {
+				b.Skip("skipping the biggest payloads in short mode")
+			}
+			input := payload(size.size)
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.Parse(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse_valid(b *testing.B) {
+	benchmarkParse(b, func(size int) string {
+		return "[\n" + benchmarkPayload(size, ",\n", validRecord) + "\n]"
+	})
+}
+
+func BenchmarkParse_repaired(b *testing.B) {
+	benchmarkParse(b, func(size int) string {
+		return "{\n" + benchmarkPayload(size, ",\n", looseRecord) + "\n}"
+	})
+}
+
+func BenchmarkParse_truncated(b *testing.B) {
+	benchmarkParse(b, func(size int) string {
+		// Cut off partway through a record, the way a log line gets cut off at its size limit.
+		return "[\n" + benchmarkPayload(size, ",\n", validRecord) + ",\n" + validRecord(-1)[:40]
+	})
+}
+
+func BenchmarkParse_ndjson(b *testing.B) {
+	benchmarkParse(b, func(size int) string {
+		return benchmarkPayload(size, "\n", ndjsonRecord)
+	}, WithDocumentFormat(DocumentsNDJSON))
+}
+
+func BenchmarkParser_Stream(b *testing.B) {
+	p := NewParser(WithDocumentFormat(DocumentsNDJSON))
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			if testing.Short() && size.size > 10<<20 {
+				b.Skip("skipping the biggest payloads in short mode")
+			}
+			input := benchmarkPayload(size.size, "\n", ndjsonRecord)
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := p.Stream(strings.NewReader(input), io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParser_StreamParallel(b *testing.B) {
+	p := NewParser(WithDocumentFormat(DocumentsNDJSON))
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			if testing.Short() && size.size > 10<<20 {
+				b.Skip("skipping the biggest payloads in short mode")
+			}
+			input := benchmarkPayload(size.size, "\n", ndjsonRecord)
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := p.StreamParallel(strings.NewReader(input), io.Discard, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Brackets that are never closed, like `[INFO` tags, used to mean scanning to the end of the input for each one.
+func BenchmarkFindEmbedded_unclosed(b *testing.B) {
+	input := "log " + benchmarkPayload(1<<20, " ", func(i int) string {
+		return fmt.Sprintf(`[tag%d {"id": %d}`, i, i)
+	})
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindEmbedded(input)
+	}
+}
@@ -0,0 +1,128 @@
+package parse
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// jsonNumberPattern is a number exactly as JSON allows it.  Unlike `IsNumber`, it won't take leading zeros, so
+// things like zip codes (`"02134"`) are never mistaken for one.
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// CoercionMode is which way we convert values between strings and JSON's own types.
+type CoercionMode int
+
+const (
+	// CoerceOff leaves values as they are.
+	CoerceOff CoercionMode = iota
+	// CoerceNative turns strings holding a number, `true`, `false` or `null` into the real thing, eg. `"42"` to
+	// `42`.  Only exact JSON spellings count, so `"042"`, `" 42"` and `"True"` are left as strings.
+	CoerceNative
+	// CoerceStrings goes the other way, turning numbers, booleans and nulls into strings, eg. `42` to `"42"`.
+	CoerceStrings
+)
+
+// CoercionOptions controls converting values between strings and JSON's own types, for services that stringify
+// everything (`{"count": "42", "enabled": "true"}`).  Each value we convert is reported as a DiagnosticCoerced.
+//
+// Include and Exclude are paths, like the ones in diagnostics (`a.b[0].c`), and cover everything under them too.
+// A `*` stands in for any single key or index, eg. `items[*].zip` or `*.id`.
+type CoercionOptions struct {
+	Mode CoercionMode
+	// Include limits coercion to these paths.  By default, it's everywhere.
+	Include []string
+	// Exclude leaves these paths alone, even if they're included, eg. IDs that need to stay strings.
+	Exclude []string
+}
+
+// pathPatterns is CoercionOptions' Include and Exclude, compiled once per parse.
+type pathPatterns struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func compilePathPatterns(patterns []string) []*regexp.Regexp {
+	result := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		// Anything under the path matches as well, but not a longer key that happens to start the same.
+		expression := "^" + strings.Join(parts, `[^.\[\]]*`) + `($|[.\[])`
+		result = append(result, regexp.MustCompile(expression))
+	}
+	return result
+}
+
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// coercible is whether the value at the path should be coerced at all.
+func (s *parseState) coercible(path string) bool {
+	if s.Coercion.Mode == CoerceOff {
+		return false
+	}
+	if s.coercionPaths == nil {
+		s.coercionPaths = &pathPatterns{
+			include: compilePathPatterns(s.Coercion.Include),
+			exclude: compilePathPatterns(s.Coercion.Exclude),
+		}
+	}
+	if len(s.coercionPaths.include) > 0 && !matchesAny(s.coercionPaths.include, path) {
+		return false
+	}
+	return !matchesAny(s.coercionPaths.exclude, path)
+}
+
+// coerce converts a string, number, boolean or null the way the CoercionMode asks.  It returns false if the value
+// is left as it was.
+func (s *parseState) coerce(input interface{}, path string) (interface{}, bool) {
+	if !s.coercible(path) {
+		return nil, false
+	}
+
+	switch s.Coercion.Mode {
+	case CoerceNative:
+		value, ok := input.(string)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case value == "true" || value == "false":
+			s.report(DiagnosticCoerced, path, -1, -1, "coerced the string %q to a boolean", value)
+			return value == "true", true
+		case value == "null":
+			s.report(DiagnosticCoerced, path, -1, -1, "coerced the string %q to null", value)
+			return nil, true
+		case jsonNumberPattern.MatchString(value):
+			s.report(DiagnosticCoerced, path, -1, -1, "coerced the string %q to a number", value)
+			return json.Number(value), true
+		}
+	case CoerceStrings:
+		var value string
+		switch v := input.(type) {
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = "false"
+			if v {
+				value = "true"
+			}
+		case nil:
+			value = "null"
+		default:
+			return nil, false
+		}
+		s.report(DiagnosticCoerced, path, -1, -1, "coerced %s to a string", value)
+		return value, true
+	}
+	return nil, false
+}
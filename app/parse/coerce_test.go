@@ -0,0 +1,108 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseState_processValue_success_coerces_strings_to_native_types(t *testing.T) {
+	input := `{"count": "42", "price": "-1.50", "enabled": "true", "deleted": "false", "parent": "null", ` +
+		`"zip": "02134", "padded": " 42", "title": "True", "name": "jane", "tags": ["1", "x"]}`
+	expected := `{
+    "count": 42,
+    "deleted": false,
+    "enabled": true,
+    "name": "jane",
+    "padded": " 42",
+    "parent": null,
+    "price": -1.50,
+    "tags": [
+        1,
+        "x"
+    ],
+    "title": "True",
+    "zip": "02134"
+}`
+
+	p := &Parser{Coercion: CoercionOptions{Mode: CoerceNative}}
+	result, diagnostics := unwrapped(t, p, input)
+
+	assert.Equal(t, expected, result)
+	assert.Len(t, diagnostics, 6)
+	assert.Contains(t, diagnostics, Diagnostic{
+		Kind:    DiagnosticCoerced,
+		Path:    "tags[0]",
+		Start:   -1,
+		End:     -1,
+		Message: `coerced the string "1" to a number`,
+	})
+}
+
+func TestParser_Parse_success_coerces_native_types_to_strings(t *testing.T) {
+	input := `{"count": 42, "enabled": true, "parent": null, "name": "jane", "nested": {"ids": [1, 2.5]}}`
+	expected := `{
+    "count": "42",
+    "enabled": "true",
+    "name": "jane",
+    "nested": {
+        "ids": [
+            "1",
+            "2.5"
+        ]
+    },
+    "parent": "null"
+}`
+
+	p := &Parser{Coercion: CoercionOptions{Mode: CoerceStrings}}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParseState_processValue_success_coerces_only_included_paths(t *testing.T) {
+	input := `{"id": "123", "user": {"id": "456", "age": "30"}, "items": [{"zip": "12345", "qty": "2"}], ` +
+		`"identifier": "789"}`
+
+	tests := []struct {
+		name     string
+		options  CoercionOptions
+		expected string
+	}{
+		{
+			"exclude",
+			CoercionOptions{Mode: CoerceNative, Exclude: []string{"id", "*.id", "items[*].zip"}},
+			`{"id": "123", "user": {"id": "456", "age": 30}, "items": [{"zip": "12345", "qty": 2}], ` +
+				`"identifier": 789}`,
+		},
+		{
+			"include",
+			CoercionOptions{Mode: CoerceNative, Include: []string{"user", "items[0].qty"}},
+			`{"id": "123", "user": {"id": 456, "age": 30}, "items": [{"zip": "12345", "qty": 2}], ` +
+				`"identifier": "789"}`,
+		},
+		{
+			"include_and_exclude",
+			CoercionOptions{Mode: CoerceNative, Include: []string{"user"}, Exclude: []string{"user.id"}},
+			`{"id": "123", "user": {"id": "456", "age": 30}, "items": [{"zip": "12345", "qty": "2"}], ` +
+				`"identifier": "789"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{Coercion: tt.options}
+			result, _ := unwrapped(t, p, input)
+
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseState_processValue_success_coercion_off_by_default(t *testing.T) {
+	input := `{"count": "42", "enabled": true}`
+
+	result, diagnostics := unwrapped(t, &Parser{}, input)
+
+	assert.JSONEq(t, input, result)
+	assert.Empty(t, diagnostics)
+}
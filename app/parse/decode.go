@@ -0,0 +1,554 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DuplicateKeyPolicy is what we do when the same key shows up more than once in an object.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateLastWins keeps the last value, the same as `json.Unmarshal` does.
+	DuplicateLastWins DuplicateKeyPolicy = iota
+	// DuplicateFirstWins keeps the first value, and ignores the rest.
+	DuplicateFirstWins
+	// DuplicateError fails the parse with a DuplicateKeyError.
+	DuplicateError
+	// DuplicateMerge merges the values if they're both objects, otherwise the last one wins.
+	DuplicateMerge
+	// DuplicateCollect gathers every value into an array, in the order they showed up.
+	DuplicateCollect
+)
+
+// DuplicateKeyError is returned for duplicate keys when the policy is DuplicateError.
+type DuplicateKeyError struct {
+	Path string
+	// Start is the byte offset of the duplicate, see `Diagnostic` for what it's an offset into.
+	Start int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Start < 0 {
+		return fmt.Sprintf("duplicate key %q", e.Path)
+	}
+	return fmt.Sprintf("duplicate key %q at offset %d", e.Path, e.Start)
+}
+
+// keyOrder is the order keys went into an object, which a Go map won't tell us, and where each key was found.
+// It holds on to the object itself, so it can't be collected and have its address reused by another object
+// while we're still parsing.
+type keyOrder struct {
+	object map[string]interface{}
+	keys   []string
+	// unique is true as long as each key is only in keys once, which it is unless the order was set for us.
+	unique bool
+	// spans is where each of the keys was found in the input, in the same order, or nil if we don't know.
+	spans [][2]int
+	// index is where each key is in keys, which we only work out if we're asked for a span.
+	index map[string]int
+	// collected is the keys we've already gathered duplicates into an array for.
+	collected map[string]bool
+	// repeated is the `[]` keys that turned up more than once, whose value is the list of every value they had.
+	repeated map[string]bool
+}
+
+// minOrderBlock and maxOrderBlock are how many keyOrders we allocate at once.  Every object gets one, so doing it one
+// at a time adds up, but a small document only has a few objects, so we start small and go up as there are more.
+const (
+	minOrderBlock = 8
+	maxOrderBlock = 256
+)
+
+// valueDecoder unmarshals JSON the same as `unmarshal` does, with numbers kept as `json.Number`, in one pass over
+// the text.  We don't use `json.Decoder` as going token by token through it allocates for every one of them.
+type valueDecoder struct {
+	s    *parseState
+	data string
+	pos  int
+	// offset is where the data starts in the input, or -1 if we don't know.
+	offset int
+	// base is the path to the data, and parts the keys and indexes below it to where we are.  We only join them up
+	// into a path when we need one, for a duplicate key.
+	base  string
+	parts []pathPart
+	// keys and spans are the keys of the objects we're partway through, and where they were, so we can give each
+	// object a slice of them that's just the right size once we're done with it.
+	keys  []string
+	spans [][2]int
+}
+
+// pathPart is a key, or if index isn't -1, an index into an array.
+type pathPart struct {
+	key   string
+	index int
+}
+
+// keyAt is a key, and where it was.
+type keyAt struct {
+	key        string
+	start, end int
+}
+
+// errInvalidJSON means the decoder ran into something that isn't JSON.  We leave it to `encoding/json` to say what.
+var errInvalidJSON = errors.New("invalid JSON")
+
+// decode unmarshals the data the same as `unmarshal`, but also spots duplicate keys that `json.Unmarshal` would
+// quietly drop, and remembers the order keys went in and where they were.  The offset is where the data starts in
+// the input, or -1 if we don't know.
+func (s *parseState) decode(data string, offset int, path string) (interface{}, error) {
+	d := &valueDecoder{s: s, data: data, offset: offset, base: path}
+	result, err := d.value()
+	if err == nil {
+		d.skipWhitespace()
+		if d.pos < len(d.data) {
+			err = errInvalidJSON
+		}
+	}
+	if err == errInvalidJSON {
+		var value interface{}
+		if err = json.Unmarshal([]byte(data), &value); err == nil {
+			err = errInvalidJSON
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *valueDecoder) skipWhitespace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// path is where we are, eg. `a.b[0].c`.
+func (d *valueDecoder) path() string {
+	path := d.base
+	for _, part := range d.parts {
+		if part.index >= 0 {
+			path = indexPath(path, part.index)
+		} else {
+			path = joinPath(path, part.key)
+		}
+	}
+	return path
+}
+
+func (d *valueDecoder) value() (interface{}, error) {
+	d.skipWhitespace()
+	if d.pos >= len(d.data) {
+		return nil, errInvalidJSON
+	}
+	switch c := d.data[d.pos]; {
+	case c == '{':
+		return d.object()
+	case c == '[':
+		return d.array()
+	case c == '"':
+		return d.string()
+	case c == '-' || c >= '0' && c <= '9':
+		return d.number()
+	case strings.HasPrefix(d.data[d.pos:], "true"):
+		d.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(d.data[d.pos:], "false"):
+		d.pos += len("false")
+		return false, nil
+	case strings.HasPrefix(d.data[d.pos:], "null"):
+		d.pos += len("null")
+		return nil, nil
+	}
+	return nil, errInvalidJSON
+}
+
+func (d *valueDecoder) object() (interface{}, error) {
+	d.pos++
+	object := map[string]interface{}{}
+	order := d.s.newOrder(object)
+	d.skipWhitespace()
+	if d.pos < len(d.data) && d.data[d.pos] == '}' {
+		d.pos++
+		return object, nil
+	}
+	mark := len(d.keys)
+	var duplicates []keyAt
+	for {
+		d.skipWhitespace()
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return nil, errInvalidJSON
+		}
+		start := d.pos
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		end := d.pos
+		if d.offset >= 0 {
+			start += d.offset
+			end += d.offset
+		} else {
+			start, end = -1, -1
+		}
+		d.skipWhitespace()
+		if d.pos >= len(d.data) || d.data[d.pos] != ':' {
+			return nil, errInvalidJSON
+		}
+		d.pos++
+
+		d.parts = append(d.parts, pathPart{key: key, index: -1})
+		value, err := d.value()
+		d.parts = d.parts[:len(d.parts)-1]
+		if err != nil {
+			return nil, err
+		}
+
+		if existing, ok := object[key]; !ok {
+			d.keys = append(d.keys, key)
+			d.spans = append(d.spans, [2]int{start, end})
+		} else if d.s.DotNotation.appends(key) {
+			// Form style `tags[]` keys are meant to turn up more than once, each one adding to the array.
+			value = d.s.repeat(object, key, existing, value)
+			duplicates = append(duplicates, keyAt{key, start, end})
+		} else {
+			value, err = d.s.resolveDuplicate(object, key, joinPath(d.path(), key), existing, value, start, end)
+			if err != nil {
+				return nil, err
+			}
+			duplicates = append(duplicates, keyAt{key, start, end})
+		}
+		object[key] = value
+
+		d.skipWhitespace()
+		if d.pos >= len(d.data) {
+			return nil, errInvalidJSON
+		}
+		d.pos++
+		switch d.data[d.pos-1] {
+		case ',':
+			continue
+		case '}':
+			order.keys = append([]string(nil), d.keys[mark:]...)
+			order.spans = append([][2]int(nil), d.spans[mark:]...)
+			d.keys = d.keys[:mark]
+			d.spans = d.spans[:mark]
+			// A duplicate is reported where it was last seen.
+			for _, duplicate := range duplicates {
+				order.spans[order.find(duplicate.key)] = [2]int{duplicate.start, duplicate.end}
+			}
+			return object, nil
+		}
+		return nil, errInvalidJSON
+	}
+}
+
+func (d *valueDecoder) array() (interface{}, error) {
+	d.pos++
+	array := []interface{}{}
+	d.skipWhitespace()
+	if d.pos < len(d.data) && d.data[d.pos] == ']' {
+		d.pos++
+		return array, nil
+	}
+	for {
+		d.parts = append(d.parts, pathPart{index: len(array)})
+		value, err := d.value()
+		d.parts = d.parts[:len(d.parts)-1]
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		d.skipWhitespace()
+		if d.pos >= len(d.data) {
+			return nil, errInvalidJSON
+		}
+		d.pos++
+		switch d.data[d.pos-1] {
+		case ',':
+			continue
+		case ']':
+			return array, nil
+		}
+		return nil, errInvalidJSON
+	}
+}
+
+// string reads a string, starting at its opening quote.  Most strings don't have anything in them to unescape, so
+// we hand those back as they are, and leave the rest to `encoding/json`.
+func (d *valueDecoder) string() (string, error) {
+	start := d.pos
+	plain := true
+	ascii := true
+	for i := start + 1; i < len(d.data); i++ {
+		switch c := d.data[i]; {
+		case c == '"':
+			d.pos = i + 1
+			text := d.data[start+1 : i]
+			if plain && (ascii || utf8.ValidString(text)) {
+				return text, nil
+			}
+			var unescaped string
+			if err := json.Unmarshal([]byte(d.data[start:d.pos]), &unescaped); err != nil {
+				return "", errInvalidJSON
+			}
+			return unescaped, nil
+		case c == '\\':
+			plain = false
+			i++
+		case c < 0x20:
+			return "", errInvalidJSON
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return "", errInvalidJSON
+}
+
+// number reads a number, held to JSON's syntax for them.
+func (d *valueDecoder) number() (interface{}, error) {
+	start := d.pos
+	if d.pos < len(d.data) && d.data[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos < len(d.data) && d.data[d.pos] == '0':
+		d.pos++
+	case d.digits() == 0:
+		return nil, errInvalidJSON
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		if d.digits() == 0 {
+			return nil, errInvalidJSON
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if d.digits() == 0 {
+			return nil, errInvalidJSON
+		}
+	}
+	return json.Number(d.data[start:d.pos]), nil
+}
+
+// digits skips over a run of digits, and says how many there were.
+func (d *valueDecoder) digits() int {
+	start := d.pos
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	return d.pos - start
+}
+
+func (s *parseState) resolveDuplicate(object map[string]interface{}, key, path string, existing, value interface{}, start, end int) (interface{}, error) {
+	s.report(DiagnosticDuplicateKey, path, start, end, "duplicate key %q", path)
+
+	switch s.DuplicateKeys {
+	case DuplicateFirstWins:
+		return existing, nil
+	case DuplicateError:
+		return nil, &DuplicateKeyError{Path: path, Start: start}
+	case DuplicateMerge:
+		existingObj, existingIsObj := existing.(map[string]interface{})
+		valueObj, valueIsObj := value.(map[string]interface{})
+		if existingIsObj && valueIsObj {
+			err := s.mergeDuplicates(existingObj, valueObj, path, start, end)
+			return existingObj, err
+		}
+		return value, nil
+	case DuplicateCollect:
+		// Array items don't have a key to remember we've collected them under, so each duplicate starts afresh.
+		if object == nil {
+			return []interface{}{existing, value}, nil
+		}
+		order := s.order(object)
+		if order.collected[key] {
+			return append(existing.([]interface{}), value), nil
+		}
+		if order.collected == nil {
+			order.collected = map[string]bool{}
+		}
+		order.collected[key] = true
+		return []interface{}{existing, value}, nil
+	default:
+		return value, nil
+	}
+}
+
+// repeat gathers up the values of a `[]` key that turned up more than once.
+func (s *parseState) repeat(object map[string]interface{}, key string, existing, value interface{}) interface{} {
+	order := s.order(object)
+	if order.repeated[key] {
+		return append(existing.([]interface{}), value)
+	}
+	if order.repeated == nil {
+		order.repeated = map[string]bool{}
+	}
+	order.repeated[key] = true
+	return []interface{}{existing, value}
+}
+
+// mergeDuplicates moves everything in `value` over into `existing`, merging any objects they both have under the
+// same key, and going by the duplicate key policy for anything else they both have.
+func (s *parseState) mergeDuplicates(existing, value map[string]interface{}, path string, start, end int) error {
+	for _, key := range s.orderedKeys(value) {
+		val := value[key]
+		keyPath := joinPath(path, key)
+		current, ok := existing[key]
+		if !ok {
+			s.setKey(existing, key, val)
+			continue
+		}
+
+		currentObj, currentIsObj := current.(map[string]interface{})
+		valObj, valIsObj := val.(map[string]interface{})
+		if currentIsObj && valIsObj {
+			err := s.mergeDuplicates(currentObj, valObj, keyPath, start, end)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		resolved, err := s.resolveDuplicate(existing, key, keyPath, current, val, start, end)
+		if err != nil {
+			return err
+		}
+		existing[key] = resolved
+	}
+	return nil
+}
+
+func (s *parseState) order(object map[string]interface{}) *keyOrder {
+	if order, ok := s.keyOrders[reflect.ValueOf(object).Pointer()]; ok {
+		return order
+	}
+	return s.newOrder(object)
+}
+
+// newOrder starts remembering the order of the keys in an object we've just made.
+func (s *parseState) newOrder(object map[string]interface{}) *keyOrder {
+	if s.keyOrders == nil {
+		s.keyOrders = map[uintptr]*keyOrder{}
+	}
+	if len(s.orders) == 0 {
+		s.orders = make([]keyOrder, min(max(len(s.keyOrders), minOrderBlock), maxOrderBlock))
+	}
+	order := &s.orders[0]
+	s.orders = s.orders[1:]
+	order.object = object
+	order.unique = true
+	s.keyOrders[reflect.ValueOf(object).Pointer()] = order
+	return order
+}
+
+// add adds a key that isn't in the object yet, and where it was found.
+func (o *keyOrder) add(key string, start, end int) {
+	if o.index != nil {
+		o.index[key] = len(o.keys)
+	}
+	if o.spans == nil && len(o.keys) == 0 {
+		o.spans = [][2]int{}
+	}
+	if o.spans != nil {
+		o.spans = append(o.spans, [2]int{start, end})
+	}
+	o.keys = append(o.keys, key)
+}
+
+// find is where the key is in keys, or -1 if it isn't.
+func (o *keyOrder) find(key string) int {
+	if o.index == nil {
+		o.index = make(map[string]int, len(o.keys))
+		for i, k := range o.keys {
+			if _, ok := o.index[k]; !ok {
+				o.index[k] = i
+			}
+		}
+	}
+	if i, ok := o.index[key]; ok {
+		return i
+	}
+	return -1
+}
+
+// setKey sets the value in the object, remembering the order the key went in.
+func (s *parseState) setKey(object map[string]interface{}, key string, value interface{}) {
+	if _, ok := object[key]; !ok {
+		s.order(object).add(key, -1, -1)
+	}
+	object[key] = value
+}
+
+// setOrder replaces the order the object's keys went in.
+func (s *parseState) setOrder(object map[string]interface{}, keys []string) {
+	order := s.order(object)
+	order.keys = keys
+	order.unique = false
+	order.spans = nil
+	order.index = nil
+}
+
+// keySpan is where the key was found in the input, or -1 if we don't know.
+func (s *parseState) keySpan(object map[string]interface{}, key string) (int, int) {
+	order := s.order(object)
+	if order.spans == nil {
+		return -1, -1
+	}
+	i := order.find(key)
+	if i < 0 {
+		return -1, -1
+	}
+	return order.spans[i][0], order.spans[i][1]
+}
+
+// orderedKeys returns the object's keys in the order they went in.  Any we didn't see go in (because some other
+// code built the object) come after, sorted.  The keys mustn't be changed, they can be the ones we're holding on to.
+func (s *parseState) orderedKeys(object map[string]interface{}) []string {
+	order := s.order(object)
+	if order.unique && len(order.keys) == len(object) && hasKeys(object, order.keys) {
+		return order.keys
+	}
+
+	keys := make([]string, 0, len(object))
+	seen := make(map[string]bool, len(object))
+	for _, key := range order.keys {
+		if _, ok := object[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range object {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+func hasKeys(object map[string]interface{}, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := object[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_ParseResult_success_last_duplicate_wins_by_default(t *testing.T) {
+	input := `{"a": 1,"a": 2}`
+	expected := `{
+    "a": 2
+}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.Output)
+	assert.Equal(t, []Diagnostic{{
+		Kind:    DiagnosticDuplicateKey,
+		Path:    "a",
+		Start:   8,
+		End:     11,
+		Message: `duplicate key "a"`,
+	}}, result.Diagnostics)
+}
+
+func TestParser_Parse_success_first_duplicate_wins(t *testing.T) {
+	input := `{"a": 1, "nested": {"b": true, "b": false}, "a": 2}`
+	expected := `{
+    "a": 1,
+    "nested": {
+        "b": true
+    }
+}`
+
+	p := &Parser{DuplicateKeys: DuplicateFirstWins}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_failure_duplicate_error(t *testing.T) {
+	input := `{"a": {"b": 1, "b": 2}}`
+
+	p := &Parser{DuplicateKeys: DuplicateError}
+	result, err := p.Parse(input)
+
+	assert.Equal(t, "", result)
+	dupErr, ok := err.(*DuplicateKeyError)
+	assert.True(t, ok)
+	assert.Equal(t, "a.b", dupErr.Path)
+}
+
+func TestParser_Parse_success_duplicate_objects_merged(t *testing.T) {
+	input := `{"a": {"x": 1, "y": 1}, "a": {"y": 2, "z": 2}, "b": 1, "b": 2}`
+	expected := `{
+    "a": {
+        "x": 1,
+        "y": 2,
+        "z": 2
+    },
+    "b": 2
+}`
+
+	p := &Parser{DuplicateKeys: DuplicateMerge}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_success_duplicates_collected(t *testing.T) {
+	input := `{"a": 1, "a": "two", "a": [3], "b": 1}`
+	expected := `{
+    "a": [
+        1,
+        "two",
+        [
+            3
+        ]
+    ],
+    "b": 1
+}`
+
+	p := &Parser{DuplicateKeys: DuplicateCollect}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_ParseResult_success_dot_notation_collision_is_a_duplicate(t *testing.T) {
+	input := `{"a": {"b": 1}, "a.b": 2}`
+	expected := `{
+    "a": {
+        "b": 1
+    }
+}`
+
+	p := &Parser{DuplicateKeys: DuplicateFirstWins}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.Output)
+	assert.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, DiagnosticDuplicateKey, result.Diagnostics[0].Kind)
+	assert.Equal(t, "a.b", result.Diagnostics[0].Path)
+}
+
+func TestParser_ParseResult_success_dot_notation_halves_of_an_object_are_not_duplicates(t *testing.T) {
+	input := `{"a.c": 3, "a": {"b": 1}}`
+	expected := `{
+    "a": {
+        "b": 1,
+        "c": 3
+    }
+}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.Output)
+	assert.Empty(t, result.Diagnostics)
+}
@@ -0,0 +1,67 @@
+package parse
+
+import "fmt"
+
+// DiagnosticKind is the kind of thing we noticed, or had to do, while parsing.
+type DiagnosticKind string
+
+const (
+	// DiagnosticTruncated means the input stopped partway through, and we closed it off ourselves.
+	DiagnosticTruncated DiagnosticKind = "truncated"
+	// DiagnosticDuplicateKey means a key showed up more than once in the same object.
+	DiagnosticDuplicateKey DiagnosticKind = "duplicate_key"
+	// DiagnosticDotConflict means expanding a dot notation key ran into a value that isn't an object.
+	DiagnosticDotConflict DiagnosticKind = "dot_conflict"
+	// DiagnosticDecoded means we unpacked JSON that was stored in a string.
+	DiagnosticDecoded DiagnosticKind = "decoded"
+	// DiagnosticJWT means we decoded a JSON Web Token.  The message says whether its signature was verified.
+	DiagnosticJWT DiagnosticKind = "jwt"
+	// DiagnosticCoerced means we converted a value between a string and a number, boolean or null.
+	DiagnosticCoerced DiagnosticKind = "coerced"
+	// DiagnosticDynamoDB means we unwrapped a DynamoDB item's attribute values (`{"N": "5"}`) into plain JSON.
+	DiagnosticDynamoDB DiagnosticKind = "dynamodb"
+)
+
+// Diagnostic is something worth knowing about the input, that didn't stop us from parsing it.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	// Path is where in the data the diagnostic is about, eg. `a.b[0].c`.  It's empty for the top level value.
+	Path string
+	// Start and End are byte offsets into the input the diagnostic is about.  They're the same when it's about a
+	// single point, like the end of the input.  If we had to strip Markdown quote or list markers from the input,
+	// these are offsets into the stripped text instead.  For anything found after the input was repaired, they're
+	// mapped back to the key or value in the input it came from.  They're -1 when we can't place it at all, like for
+	// JSON unpacked out of a string value.
+	Start   int
+	End     int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Start < 0 {
+		return fmt.Sprintf("%s: %s", d.Kind, d.Message)
+	}
+	return fmt.Sprintf("%s at %d: %s", d.Kind, d.Start, d.Message)
+}
+
+// Result is the formatted output, along with the diagnostics we collected, and the repairs we made, on the way.
+// Ambiguities is everywhere we had to guess, see `Parser.Interpretations` for what the other guesses would give.
+// Text is what the offsets in them are into.  That's the input, unless we had to strip Markdown quote or list
+// markers off it, in which case it's what was left.
+type Result struct {
+	Output      string
+	Diagnostics []Diagnostic
+	Repairs     []Repair
+	Ambiguities []Ambiguity
+	Text        string
+}
+
+func (s *parseState) report(kind DiagnosticKind, path string, start, end int, format string, args ...interface{}) {
+	s.diagnostics = append(s.diagnostics, Diagnostic{
+		Kind:    kind,
+		Path:    path,
+		Start:   start,
+		End:     end,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,296 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DocumentFormat is how we write out input that held more than one top level document.
+type DocumentFormat int
+
+const (
+	// DocumentsPretty writes each document pretty printed, separated by a blank line.
+	DocumentsPretty DocumentFormat = iota
+	// DocumentsArray collects every document into a single JSON array.
+	DocumentsArray
+	// DocumentsNDJSON writes each document compacted onto its own line.
+	DocumentsNDJSON
+)
+
+// DocumentError is a failure to parse one document out of several.  It doesn't stop the others from being parsed.
+type DocumentError struct {
+	// Index is the zero based position of the document in the input.
+	Index int
+	// Line is the line of the input the document starts on, starting at 1.
+	Line int
+	Err  error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d (line %d): %s", e.Index+1, e.Line, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// DocumentErrors is returned when some of the documents in the input couldn't be parsed, or were cut off.  The
+// output returned alongside it still holds every document that could be parsed, including what there was of the
+// ones that were cut off, whose errors are a TruncatedError.
+type DocumentErrors []*DocumentError
+
+func (e DocumentErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// document is a single top level value, sliced out of the input.
+type document struct {
+	text string
+	// offset is where this document starts in the text it was split out of.
+	offset int
+}
+
+// splitDocuments breaks the input up if it holds several top level documents, either back to back
+// (`{"a":1}{"a":2}`) or one per line (NDJSON / JSON Lines).  Anything else comes back as a single document,
+// and is left to the regular repair logic.
+//
+// A bad document mustn't take the rest down with it, so we split by line as long as most lines are documents, and
+// the odd bad line is left for parseDocuments to report.  Failing that, a last document that's cut off is split
+// off from the ones before it, so only it gets closed.
+func splitDocuments(input string) []document {
+	documents, truncated := splitConcatenatedDocuments(input)
+	if len(documents) > 1 && !truncated {
+		return documents
+	}
+	lines := splitDocumentLines(input)
+	if len(lines) > 1 {
+		return lines
+	}
+	if len(documents) > 1 {
+		return documents
+	}
+	return []document{{text: input}}
+}
+
+// splitConcatenatedDocuments only deals in objects and arrays, as those are the only things we can find the
+// edges of without knowing what's inside them.  If we see anything else between them we bail out.  If the last one
+// is never closed, it's kept as it is and we return true, so it can be closed off on its own.
+func splitConcatenatedDocuments(input string) ([]document, bool) {
+	var documents []document
+	depth := 0
+	start := 0
+	inString := false
+	escaped := false
+
+	for i, r := range input {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case r == '"' && depth > 0:
+			inString = true
+		case startsComplexDataStructure(r):
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case endsComplexDataStructure(r):
+			depth--
+			if depth < 0 {
+				return nil, false
+			}
+			if depth == 0 {
+				documents = append(documents, document{text: input[start : i+1], offset: start})
+			}
+		case depth == 0 && !isWhitespace(r):
+			return nil, false
+		}
+	}
+	if depth != 0 {
+		if len(documents) == 0 {
+			return nil, false
+		}
+		return append(documents, document{text: input[start:], offset: start}), true
+	}
+	return documents, false
+}
+
+// splitDocumentLines picks up JSON Lines input, which can hold any kind of value per line.  We hold each line to
+// being valid JSON here, since plenty of the half-baked input we get is written one `key: value` per line.  The
+// exception is a line or two that's bad among lines that are otherwise objects and arrays, like an export with a
+// line cut off partway through, which we keep so it's reported on its own.
+func splitDocumentLines(input string) []document {
+	// Count them up first, so we don't go to the trouble of building the documents for input that isn't JSON
+	// Lines, which is most of it.
+	lines, invalid, structures := 0, 0, 0
+	eachLine(input, func(line string, offset int) {
+		lines++
+		switch valid, structure := isDocumentLine(line); {
+		case !valid:
+			invalid++
+		case structure:
+			structures++
+		}
+	})
+	// A scalar on its own line could just as well be the rest of a `key: value` line, so only whole objects and
+	// arrays count towards it being JSON Lines.
+	if lines == 0 || invalid > 0 && (structures < 2 || structures*2 <= lines) {
+		return nil
+	}
+
+	documents := make([]document, 0, lines)
+	eachLine(input, func(line string, offset int) {
+		documents = append(documents, document{text: line, offset: offset})
+	})
+	return documents
+}
+
+// eachLine calls the function with each line that has something on it, trimmed, and where it starts.
+func eachLine(input string, line func(line string, offset int)) {
+	for offset := 0; offset < len(input); {
+		end := strings.IndexByte(input[offset:], '\n')
+		if end < 0 {
+			end = len(input)
+		} else {
+			end += offset
+		}
+		text := strings.TrimLeft(input[offset:end], " \t\r")
+		start := end - len(text)
+		text = strings.TrimRight(text, " \t\r")
+		if text != "" {
+			line(text, start)
+		}
+		offset = end + 1
+	}
+}
+
+// isDocumentLine is whether a line is a document on its own, and if so whether it's an object or array.  Most lines
+// that aren't can be told apart by how they start and end, which saves asking `encoding/json`.
+func isDocumentLine(line string) (bool, bool) {
+	first, last := line[0], line[len(line)-1]
+	switch {
+	case first == '{' && last == '}', first == '[' && last == ']':
+		return json.Valid([]byte(line)), true
+	case first == '"' && last == '"', first == '-', first >= '0' && first <= '9':
+		return json.Valid([]byte(line)), false
+	case line == "true", line == "false", line == "null":
+		return true, false
+	}
+	return false, false
+}
+
+func (s *parseState) parseDocuments(documents []document, input string) (string, error) {
+	base := s.locate(input)
+	var parsed []interface{}
+	var errs DocumentErrors
+	lines := NewLineCounter(input)
+	for i, doc := range documents {
+		if err := s.cancelled(); err != nil {
+			return "", err
+		}
+		data, err := s.parseDocument(doc.text, base+doc.offset)
+		if err != nil {
+			errs = append(errs, &DocumentError{Index: i, Line: lines.Line(doc.offset), Err: err})
+			// A document that was cut off is still in the output, as much of it as there was.
+			if !isTruncated(err) {
+				continue
+			}
+		}
+		parsed = append(parsed, data)
+	}
+
+	result, err := s.formatDocuments(parsed)
+	if err != nil {
+		return "", err
+	}
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+// LineCounter finds the line offsets into a text are on, like those in a Diagnostic or Repair.  It counts on from
+// the last one it was asked about, rather than from the start of the text each time, so asking about them in order
+// only goes over the text once.  Asking about an earlier offset starts over from the start.
+type LineCounter struct {
+	input   string
+	line    int
+	counted int
+	// column is how many characters we've counted since the start of the line.
+	column int
+}
+
+// NewLineCounter makes a LineCounter for the text.
+func NewLineCounter(text string) *LineCounter {
+	return &LineCounter{input: text}
+}
+
+// Line is the line, starting at 1, the offset is on.
+func (c *LineCounter) Line(offset int) int {
+	line, _ := c.Position(offset)
+	return line
+}
+
+// Position is the line and column, both starting at 1, the offset is at.  Columns are counted in characters.  An
+// offset we couldn't place (-1) is at the start, and one past the end is at the end.
+func (c *LineCounter) Position(offset int) (int, int) {
+	offset = min(max(offset, 0), len(c.input))
+	if offset < c.counted {
+		*c = LineCounter{input: c.input}
+	}
+	text := c.input[c.counted:offset]
+	if newline := strings.LastIndexByte(text, '\n'); newline >= 0 {
+		c.line += strings.Count(text, "\n")
+		c.column = 0
+		text = text[newline+1:]
+	}
+	c.column += utf8.RuneCountInString(text)
+	c.counted = offset
+	return c.line + 1, c.column + 1
+}
+
+func (s *parseState) formatDocuments(documents []interface{}) (string, error) {
+	if len(documents) == 0 {
+		return "", nil
+	}
+
+	switch s.DocumentFormat {
+	case DocumentsArray:
+		return s.marshal(documents)
+	case DocumentsNDJSON:
+		lines := make([]string, len(documents))
+		for i, data := range documents {
+			line, err := s.marshalCompact(data)
+			if err != nil {
+				return "", err
+			}
+			lines[i] = line
+		}
+		return strings.Join(lines, "\n"), nil
+	default:
+		formatted := make([]string, len(documents))
+		for i, data := range documents {
+			result, err := s.marshal(data)
+			if err != nil {
+				return "", err
+			}
+			formatted[i] = result
+		}
+		return strings.Join(formatted, "\n\n"), nil
+	}
+}
@@ -0,0 +1,209 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_concatenated_documents(t *testing.T) {
+	input := `{"a":1}{"a":2} [3]`
+	expected := `{
+    "a": 1
+}
+
+{
+    "a": 2
+}
+
+[
+    3
+]`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_json_lines_with_scalars(t *testing.T) {
+	input := `{"a": 1}
+"two"
+3
+`
+	expected := `{
+    "a": 1
+}
+
+"two"
+
+3`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_repairs_each_concatenated_document(t *testing.T) {
+	input := `{a: 1}
+{a: 'two'}`
+	expected := `{
+    "a": 1
+}
+
+{
+    "a": "two"
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_success_documents_as_array(t *testing.T) {
+	input := `{"a":1}{"a":2}`
+	expected := `[
+    {
+        "a": 1
+    },
+    {
+        "a": 2
+    }
+]`
+
+	p := &Parser{DocumentFormat: DocumentsArray}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_success_documents_as_ndjson(t *testing.T) {
+	input := `{"a": 1}
+{"b": [1, 2]}`
+	expected := `{"a":1}
+{"b":[1,2]}`
+
+	p := &Parser{DocumentFormat: DocumentsNDJSON}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_failure_bad_document_does_not_stop_the_rest(t *testing.T) {
+	input := `{"a": 1}
+{"b"}
+{"c": 3}`
+	expected := `{
+    "a": 1
+}
+
+{
+    "c": 3
+}`
+
+	result, err := Parse(input)
+
+	assert.Equal(t, expected, result)
+	docErrs, ok := err.(DocumentErrors)
+	assert.True(t, ok)
+	assert.Len(t, docErrs, 1)
+	assert.Equal(t, 1, docErrs[0].Index)
+	assert.Equal(t, 2, docErrs[0].Line)
+}
+
+func TestParse_success_key_value_lines_are_not_documents(t *testing.T) {
+	input := `line1: 1
+line2: 2`
+	expected := `{
+    "line1": 1,
+    "line2": 2
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_closes_truncated_last_line(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":2}\n{\"a\":3, \"b"
+
+	result, err := NewParser(WithDocumentFormat(DocumentsNDJSON)).ParseResult(input)
+
+	assert.Equal(t, DocumentErrors{{Index: 2, Line: 3, Err: &TruncatedError{Offset: len(input)}}}, err)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n{\"a\":3}", result.Output)
+	assert.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, DiagnosticTruncated, result.Diagnostics[0].Kind)
+	assert.Equal(t, len(input), result.Diagnostics[0].Start)
+}
+
+func TestParse_failure_bad_line_does_not_stop_the_rest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"middle", "{\"a\":1}\nnot json at all\n{\"a\":2}\n{\"a\":3}", 2},
+		{"last", "{\"a\":1}\n{\"a\":2}\n{\"a\":3}\nnot json at all", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input, WithDocumentFormat(DocumentsNDJSON))
+
+			assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n{\"a\":3}", result)
+			docErrs, ok := err.(DocumentErrors)
+			assert.True(t, ok)
+			assert.Len(t, docErrs, 1)
+			assert.Equal(t, tt.line, docErrs[0].Line)
+		})
+	}
+}
+
+func TestParse_failure_reports_cut_off_lines_it_closed(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":\n{\"a\":3}"
+
+	result, err := Parse(input, WithDocumentFormat(DocumentsNDJSON), WithCompact())
+
+	assert.Equal(t, "{\"a\":1}\n{}\n{\"a\":3}", result)
+	docErrs, ok := err.(DocumentErrors)
+	assert.True(t, ok)
+	assert.Len(t, docErrs, 1)
+	assert.Equal(t, 1, docErrs[0].Index)
+	assert.Equal(t, 2, docErrs[0].Line)
+	assert.True(t, isTruncated(docErrs[0]))
+}
+
+func TestParse_success_closes_truncated_concatenated_document(t *testing.T) {
+	input := `{"a":1}{"b": [1, 2`
+
+	result, err := Parse(input, WithDocumentFormat(DocumentsNDJSON))
+
+	assert.Equal(t, DocumentErrors{{Index: 1, Line: 1, Err: &TruncatedError{Offset: len(input)}}}, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":[1,2]}", result)
+}
+
+func TestParse_success_values_on_their_own_line_are_not_documents(t *testing.T) {
+	result, err := Parse("a: hello\n42\n43", WithCompact())
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":"hello4243"}`, result)
+}
+
+func TestLineCounter_Position(t *testing.T) {
+	lines := NewLineCounter("ab\ncé\n\nd")
+
+	for _, tt := range []struct{ offset, line, column int }{
+		{0, 1, 1}, {2, 1, 3}, {6, 2, 3}, {7, 3, 1}, {8, 4, 1},
+		// Going back starts over.
+		{4, 2, 2},
+		// Offsets we couldn't place are at the start, and past the end at the end.
+		{-1, 1, 1}, {100, 4, 2},
+	} {
+		line, column := lines.Position(tt.offset)
+		assert.Equal(t, tt.line, line, "line at %d", tt.offset)
+		assert.Equal(t, tt.column, column, "column at %d", tt.offset)
+	}
+}
@@ -0,0 +1,507 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DotConflictPolicy is what we do when expanding a dot notation key runs into a value that doesn't fit, like
+// `a.b: 2` alongside `a: 1`.  There's no way to have both, so something has to give.
+type DotConflictPolicy int
+
+const (
+	// DotConflictKeepDotted leaves the dot notation key as it is, rather than expanding it.  Plain keys always
+	// win over dot notation ones, and between two dot notation keys, the first one in the input wins.
+	DotConflictKeepDotted DotConflictPolicy = iota
+	// DotConflictKeepScalar turns the value in the way into an object, keeping the value under the ScalarKey.  For
+	// `[]` it turns it into an array instead, with the value as the first item.
+	DotConflictKeepScalar
+	// DotConflictOverwrite lets whichever of the two came last in the input win.
+	DotConflictOverwrite
+	// DotConflictError fails the parse with a DotKeyConflictError.
+	DotConflictError
+)
+
+const defaultScalarKey = "_value"
+
+// maxDotIndex is the biggest array index we'll expand, so `a.99999` doesn't pad out an array with tens of
+// thousands of nulls.  Anything bigger is treated as an object key instead.
+const maxDotIndex = 10000
+
+// DotNotationOptions controls how we expand dot notation keys into nested objects and arrays.  Keys can step into
+// objects (`a.b`), index into arrays (`items.0.name` or `items[0].name`), and add to the end of an array (`tags[]`),
+// the same as lodash's `set`.  Any gaps in an array are padded out with nulls.  A `\` in front of the separator
+// (`a\.b`) keeps it as part of the key.
+type DotNotationOptions struct {
+	// Disabled leaves every key as it is.
+	Disabled bool
+	// Separator is what splits a key up, eg. `__` for environment variable style keys.  It defaults to `.`.
+	Separator string
+	// Prefixes limits expansion to keys that start with one of them, at any depth.  Keys like `k8s.io/name` or
+	// `example.com` are left alone if they don't match.  An empty list expands every key.
+	Prefixes []string
+	// Conflict is what we do when expanding a key runs into a value that doesn't fit.
+	Conflict DotConflictPolicy
+	// ScalarKey is the key DotConflictKeepScalar keeps the value under.  It defaults to `_value`.
+	ScalarKey string
+}
+
+func (o DotNotationOptions) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+func (o DotNotationOptions) scalarKey() string {
+	if o.ScalarKey == "" {
+		return defaultScalarKey
+	}
+	return o.ScalarKey
+}
+
+// expands is true if the key should be expanded, as far as being turned on and the prefixes go.
+func (o DotNotationOptions) expands(key string) bool {
+	if o.Disabled {
+		return false
+	}
+	if len(o.Prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range o.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// appends is true for keys like `tags[]` that add to the end of an array when they're expanded.
+func (o DotNotationOptions) appends(key string) bool {
+	if !o.expands(key) {
+		return false
+	}
+	segments := o.splitPath(key)
+	return segments[len(segments)-1].append
+}
+
+// DotKeyConflictError is returned for dot notation conflicts when the policy is DotConflictError.
+type DotKeyConflictError struct {
+	// Path is where the value that doesn't fit is.
+	Path string
+}
+
+func (e *DotKeyConflictError) Error() string {
+	return fmt.Sprintf("can't expand dot notation keys into %q, it already holds a value that doesn't fit", e.Path)
+}
+
+// pathSegment is one step along a dot notation key.
+type pathSegment struct {
+	key string
+	// index is the array index the key stands for, or -1 if it isn't one.  It's still just a key if it lands on
+	// an object.
+	index int
+	// append is for `[]`, which adds to the end of an array.
+	append bool
+}
+
+func (p pathSegment) wantsArray() bool {
+	return p.index >= 0 || p.append
+}
+
+func (p pathSegment) describe() string {
+	switch {
+	case p.append:
+		return "an array"
+	case p.index >= 0:
+		return "an object or array"
+	default:
+		return "an object"
+	}
+}
+
+// dotKey is a dot notation key on its way into the expanded object.
+type dotKey struct {
+	key string
+	// path is where the key is, which is what we report diagnostics against.
+	path       string
+	value      interface{}
+	start, end int
+}
+
+// splitPath breaks a key up into its segments, eg. `items[0].name` is `items`, `0` and `name`.
+func (o DotNotationOptions) splitPath(key string) []pathSegment {
+	separator := o.separator()
+	var segments []pathSegment
+	current := strings.Builder{}
+	// afterBracket is true just after a `]`, where a separator doesn't mean there's an empty key in between.
+	afterBracket := false
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		closing := -1
+		if c == '[' {
+			closing = strings.IndexByte(key[i:], ']')
+		}
+		switch {
+		case c == '\\' && strings.HasPrefix(key[i+1:], separator):
+			current.WriteString(separator)
+			i += len(separator)
+			afterBracket = false
+		case c == '\\' && i+1 < len(key) && (key[i+1] == '[' || key[i+1] == '\\'):
+			current.WriteByte(key[i+1])
+			i++
+			afterBracket = false
+		case strings.HasPrefix(key[i:], separator):
+			if !afterBracket {
+				segments = append(segments, newPathSegment(current.String()))
+			}
+			current.Reset()
+			i += len(separator) - 1
+			afterBracket = false
+		case closing > 0:
+			if current.Len() > 0 || (i > 0 && !afterBracket) {
+				segments = append(segments, newPathSegment(current.String()))
+			}
+			current.Reset()
+			inner := key[i+1 : i+closing]
+			switch {
+			case inner == "":
+				segments = append(segments, pathSegment{index: -1, append: true})
+			case len(inner) >= 2 && strings.ContainsRune(`"'`, rune(inner[0])) && inner[len(inner)-1] == inner[0]:
+				// A quoted key is always an object key, even if it's a number, like `a["0"]`.
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1], index: -1})
+			default:
+				segments = append(segments, newPathSegment(inner))
+			}
+			i += closing
+			afterBracket = true
+		default:
+			current.WriteByte(c)
+			afterBracket = false
+		}
+	}
+	if !afterBracket || current.Len() > 0 {
+		segments = append(segments, newPathSegment(current.String()))
+	}
+	return segments
+}
+
+func newPathSegment(key string) pathSegment {
+	segment := pathSegment{key: key, index: -1}
+	index, err := strconv.Atoi(key)
+	// Only the plain way of writing the number, so `007` or `+1` stay keys.
+	if err == nil && index >= 0 && index <= maxDotIndex && strconv.Itoa(index) == key {
+		segment.index = index
+	}
+	return segment
+}
+
+// anyExpand is whether any of the keys could need expanding.  Anything without a separator, bracket or escape in
+// it is a plain key, which is cheaper to check than splitting them all up.
+func (o DotNotationOptions) anyExpand(keys []string) bool {
+	separator := o.separator()
+	for _, key := range keys {
+		if o.expands(key) && (strings.Contains(key, separator) || strings.ContainsAny(key, `[\`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPlainKey is true for keys that don't need expanding at all.  A key starting with `[]` has nothing to add to,
+// so it's left as is too, and so is an empty key, which is as valid as any other.
+func (o DotNotationOptions) isPlainKey(key string, segments []pathSegment) bool {
+	if key == "" || !o.expands(key) {
+		return true
+	}
+	return segments[0].append || (len(segments) == 1 && segments[0].key == key)
+}
+
+func (s *parseState) handleDotNotation(data map[string]interface{}, path string) (map[string]interface{}, error) {
+	if s.DotNotation.Disabled {
+		return data, nil
+	}
+	keys := s.orderedKeys(data)
+	if !s.DotNotation.anyExpand(keys) {
+		// Most objects don't have any dot notation keys in them, so there's no need to build them again.
+		return data, nil
+	}
+	result := map[string]interface{}{}
+	if s.DotNotation.Conflict != DotConflictKeepDotted {
+		// Go through the keys in the order they came in, so "first" and "last" mean what they say if they collide.
+		for _, key := range keys {
+			err := s.setNestedValue(result, data, path, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	// The plain keys go in first, so a dot notation key that runs into one is the one left as is, whichever came
+	// first.  Then we put the keys back in the order they came in.
+	for _, key := range keys {
+		if s.DotNotation.isPlainKey(key, s.DotNotation.splitPath(key)) {
+			s.setKey(result, key, data[key])
+		}
+	}
+	var landed []string
+	for _, key := range keys {
+		segments := s.DotNotation.splitPath(key)
+		if s.DotNotation.isPlainKey(key, segments) {
+			landed = append(landed, key)
+			continue
+		}
+		if !fits(result, segments, s.values(data, key)[0]) {
+			start, end := s.keySpan(data, key)
+			s.report(DiagnosticDotConflict, joinPath(path, key), start, end,
+				"left %q as is, it conflicts with a value that's already there", key)
+			s.setKey(result, key, data[key])
+			landed = append(landed, key)
+			continue
+		}
+		err := s.setNestedValue(result, data, path, key)
+		if err != nil {
+			return nil, err
+		}
+		landed = append(landed, segments[0].key)
+	}
+	s.setOrder(result, landed)
+	return result, nil
+}
+
+// setNestedValue expands the key from `data` out into `result`.
+func (s *parseState) setNestedValue(result, data map[string]interface{}, parent, key string) error {
+	start, end := s.keySpan(data, key)
+	segments := s.DotNotation.splitPath(key)
+	if s.DotNotation.isPlainKey(key, segments) {
+		return s.setLeaf(result, joinPath(parent, key), key, data[key], start, end)
+	}
+	for _, value := range s.values(data, key) {
+		k := &dotKey{key: key, path: joinPath(parent, key), value: value, start: start, end: end}
+		_, err := s.setIn(result, parent, segments, k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// values is every value the key had, which is more than one for a `[]` key that was repeated.
+func (s *parseState) values(data map[string]interface{}, key string) []interface{} {
+	if s.order(data).repeated[key] {
+		if values, ok := data[key].([]interface{}); ok && len(values) > 0 {
+			return values
+		}
+	}
+	return []interface{}{data[key]}
+}
+
+// setIn sets the key's value at the end of the segments, under the node, and creates anything that's missing on
+// the way.  It returns the node, which won't be the one passed in if it had to be replaced, or it's an array that
+// grew, so the caller needs to store it.
+func (s *parseState) setIn(node interface{}, path string, segments []pathSegment, k *dotKey) (interface{}, error) {
+	segment := segments[0]
+	rest := segments[1:]
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		if segment.append {
+			return s.resolvePathConflict(node, path, segments, k)
+		}
+		childPath := joinPath(path, segment.key)
+		if len(rest) == 0 {
+			return container, s.setLeaf(container, childPath, segment.key, k.value, k.start, k.end)
+		}
+		child, ok := container[segment.key]
+		if !ok {
+			child = newContainer(rest[0])
+		}
+		child, err := s.setIn(child, childPath, rest, k)
+		if err != nil {
+			return nil, err
+		}
+		s.setKey(container, segment.key, child)
+		return container, nil
+	case []interface{}:
+		if !segment.wantsArray() {
+			return s.resolvePathConflict(node, path, segments, k)
+		}
+		i := segment.index
+		if segment.append {
+			i = len(container)
+		}
+		for len(container) <= i {
+			container = append(container, nil)
+		}
+		childPath := indexPath(path, i)
+		child := container[i]
+		if len(rest) == 0 {
+			// A null in an array is a gap we can fill, whether we padded it out or it was already there.
+			if child == nil {
+				container[i] = k.value
+				return container, nil
+			}
+			resolved, err := s.combine(nil, "", childPath, child, k.value, k.start, k.end)
+			if err != nil {
+				return nil, err
+			}
+			container[i] = resolved
+			return container, nil
+		}
+		if child == nil {
+			child = newContainer(rest[0])
+		}
+		child, err := s.setIn(child, childPath, rest, k)
+		if err != nil {
+			return nil, err
+		}
+		container[i] = child
+		return container, nil
+	default:
+		return s.resolvePathConflict(node, path, segments, k)
+	}
+}
+
+func newContainer(segment pathSegment) interface{} {
+	if segment.wantsArray() {
+		return []interface{}{}
+	}
+	return map[string]interface{}{}
+}
+
+// resolvePathConflict handles a value in the way of the next segment, like `a: 1` in the way of `a.b`, and then
+// carries on setting the key.
+func (s *parseState) resolvePathConflict(node interface{}, path string, segments []pathSegment, k *dotKey) (interface{}, error) {
+	s.report(DiagnosticDotConflict, k.path, k.start, k.end,
+		"can't expand %q, %q holds a value that isn't %s", k.key, path, segments[0].describe())
+
+	var replacement interface{}
+	switch s.DotNotation.Conflict {
+	case DotConflictKeepScalar:
+		if segments[0].append {
+			replacement = []interface{}{node}
+		} else {
+			object := map[string]interface{}{}
+			s.setKey(object, s.DotNotation.scalarKey(), node)
+			replacement = object
+		}
+	case DotConflictOverwrite:
+		// We go through the keys in order, so whatever is in the way came first.
+		replacement = newContainer(segments[0])
+	default:
+		// DotConflictKeepDotted checks the key `fits` before it gets this far, so it's only DotConflictError here.
+		return nil, &DotKeyConflictError{Path: path}
+	}
+	return s.setIn(replacement, path, segments, k)
+}
+
+// setLeaf sets the key, unless it's already there, in which case the two values are combined.
+func (s *parseState) setLeaf(data map[string]interface{}, path, key string, value interface{}, start, end int) error {
+	existing, ok := data[key]
+	if !ok {
+		s.setKey(data, key, value)
+		return nil
+	}
+
+	resolved, err := s.combine(data, key, path, existing, value, start, end)
+	if err != nil {
+		return err
+	}
+	data[key] = resolved
+	return nil
+}
+
+// combine works out what to keep when two values land in the same place.  Two objects (`a.b` alongside
+// `a: {c: 1}`) are just two halves of the same object, so we merge them, and only call it a duplicate if the same
+// key turns up in both.  An object and something that isn't one is a conflict.  The object is nil for array items.
+func (s *parseState) combine(object map[string]interface{}, key, path string, existing, value interface{}, start, end int) (interface{}, error) {
+	existingObj, existingIsObj := existing.(map[string]interface{})
+	valueObj, valueIsObj := value.(map[string]interface{})
+	if existingIsObj && valueIsObj {
+		return existingObj, s.mergeObjects(existingObj, valueObj, path, start, end)
+	}
+	if existingIsObj || valueIsObj {
+		return s.resolveDotConflict(object, key, path, existing, value, start, end)
+	}
+	return s.resolveDuplicate(object, key, path, existing, value, start, end)
+}
+
+// resolveDotConflict handles an object and a value that isn't one landing in the same place.
+func (s *parseState) resolveDotConflict(object map[string]interface{}, key, path string, existing, value interface{}, start, end int) (interface{}, error) {
+	s.report(DiagnosticDotConflict, path, start, end, "%q holds both an object and a value that isn't one", path)
+
+	switch s.DotNotation.Conflict {
+	case DotConflictKeepScalar:
+		scalarKey := s.DotNotation.scalarKey()
+		if existingObj, ok := existing.(map[string]interface{}); ok {
+			return existingObj, s.setLeaf(existingObj, joinPath(path, scalarKey), scalarKey, value, start, end)
+		}
+		result := map[string]interface{}{}
+		s.setKey(result, scalarKey, existing)
+		return result, s.mergeObjects(result, value.(map[string]interface{}), path, start, end)
+	case DotConflictOverwrite:
+		return value, nil
+	case DotConflictError:
+		return nil, &DotKeyConflictError{Path: path}
+	default:
+		// DotConflictKeepDotted checks the key `fits` before it gets this far.
+		return s.resolveDuplicate(object, key, path, existing, value, start, end)
+	}
+}
+
+// mergeObjects moves everything in `value` over into `existing`.
+func (s *parseState) mergeObjects(existing, value map[string]interface{}, path string, start, end int) error {
+	for _, key := range s.orderedKeys(value) {
+		err := s.setLeaf(existing, joinPath(path, key), key, value[key], start, end)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fits is true if setting the value at the end of the segments won't run into anything that doesn't fit.  It
+// follows the same steps as `setIn`, without changing anything.
+func fits(node interface{}, segments []pathSegment, value interface{}) bool {
+	if len(segments) == 0 {
+		return mergeable(node, value)
+	}
+	segment := segments[0]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		if segment.append {
+			return false
+		}
+		child, ok := container[segment.key]
+		return !ok || fits(child, segments[1:], value)
+	case []interface{}:
+		if !segment.wantsArray() {
+			return false
+		}
+		if segment.append || segment.index >= len(container) || container[segment.index] == nil {
+			return true
+		}
+		return fits(container[segment.index], segments[1:], value)
+	default:
+		return false
+	}
+}
+
+// mergeable is true if `combine` can put the two values together without a conflict.  Duplicates are fine, they
+// go by the duplicate key policy.
+func mergeable(existing, value interface{}) bool {
+	existingObj, existingIsObj := existing.(map[string]interface{})
+	valueObj, valueIsObj := value.(map[string]interface{})
+	if !existingIsObj || !valueIsObj {
+		return existingIsObj == valueIsObj
+	}
+	for key, val := range valueObj {
+		current, ok := existingObj[key]
+		if ok && !mergeable(current, val) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,301 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// Every collision, in both orders, since which of the two comes first used to be down to map iteration order.
+var dotConflictInputs = []struct {
+	name  string
+	input string
+}{
+	{"scalar_then_dotted", `{"a": 1, "a.b": 2}`},
+	{"dotted_then_scalar", `{"a.b": 2, "a": 1}`},
+	{"nested_scalar_then_dotted", `{"x": {"a": 1, "a.b": 2}}`},
+	{"nested_dotted_then_scalar", `{"x": {"a.b": 2, "a": 1}}`},
+}
+
+func TestParser_Parse_success_dot_conflict_keeps_dotted_key_by_default(t *testing.T) {
+	for _, tt := range dotConflictInputs {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{}
+			result, err := p.ParseResult(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, wrapIfNested(tt.name, `{"a": 1, "a.b": 2}`), result.Output)
+			assert.Len(t, result.Diagnostics, 1)
+			assert.Equal(t, DiagnosticDotConflict, result.Diagnostics[0].Kind)
+		})
+	}
+}
+
+func TestParser_Parse_success_dot_conflict_keeps_scalar(t *testing.T) {
+	for _, tt := range dotConflictInputs {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictKeepScalar}}
+			result, err := p.Parse(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, wrapIfNested(tt.name, `{"a": {"_value": 1, "b": 2}}`), result)
+		})
+	}
+}
+
+func TestParser_Parse_success_dot_conflict_keeps_scalar_under_custom_key(t *testing.T) {
+	input := `{"a.b": 2, "a": 1}`
+
+	p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictKeepScalar, ScalarKey: "#text"}}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"a": {"#text": 1, "b": 2}}`, result)
+}
+
+func TestParser_Parse_success_dot_conflict_overwrite_last_wins(t *testing.T) {
+	tests := map[string]string{
+		"scalar_then_dotted":        `{"a": {"b": 2}}`,
+		"dotted_then_scalar":        `{"a": 1}`,
+		"nested_scalar_then_dotted": `{"x": {"a": {"b": 2}}}`,
+		"nested_dotted_then_scalar": `{"x": {"a": 1}}`,
+	}
+	for _, tt := range dotConflictInputs {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictOverwrite}}
+			result, err := p.Parse(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, tests[tt.name], result)
+		})
+	}
+}
+
+func TestParser_Parse_failure_dot_conflict_error(t *testing.T) {
+	for _, tt := range dotConflictInputs {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictError}}
+			result, err := p.Parse(tt.input)
+
+			assert.Equal(t, "", result)
+			conflictErr, ok := err.(*DotKeyConflictError)
+			assert.True(t, ok)
+			assert.Equal(t, wrapIfNestedPath(tt.name, "a"), conflictErr.Path)
+		})
+	}
+}
+
+func TestParser_Parse_success_dot_conflict_between_dotted_keys(t *testing.T) {
+	inputs := []string{
+		`{"a.b": 1, "a.b.c": 2}`,
+		`{"a.b.c": 2, "a.b": 1}`,
+		`{"a": {"b": 1}, "a.b.c": 2}`,
+		`{"a.b.c": 2, "a": {"b": 1}}`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictKeepScalar}}
+			result, err := p.Parse(input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, `{"a": {"b": {"_value": 1, "c": 2}}}`, result)
+		})
+	}
+}
+
+func TestParse_success_dot_conflict_leaves_unrelated_keys_expanded(t *testing.T) {
+	input := `{"a.b.c": 2, "a.d": 3, "a": {"b": 1}}`
+	expected := `{"a": {"b": 1, "d": 3}, "a.b.c": 2}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func wrapIfNested(name, expected string) string {
+	if strings.HasPrefix(name, "nested_") {
+		return `{"x": ` + expected + `}`
+	}
+	return expected
+}
+
+func wrapIfNestedPath(name, path string) string {
+	if strings.HasPrefix(name, "nested_") {
+		return "x." + path
+	}
+	return path
+}
+
+func TestParse_success_dot_notation_indexes_into_arrays(t *testing.T) {
+	input := `{"items.0.name": "first", "items[1].id": 2, "items[1].name": "second"}`
+	expected := `{
+    "items": [
+        {
+            "name": "first"
+        },
+        {
+            "id": 2,
+            "name": "second"
+        }
+    ]
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_dot_notation_pads_gaps_with_null(t *testing.T) {
+	input := `{"items.2.name": "third", "items.0.name": "first"}`
+	expected := `{"items": [{"name": "first"}, null, {"name": "third"}]}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParse_success_dot_notation_appends_repeated_keys(t *testing.T) {
+	input := `{"a.tags[]": "x", "a.tags[]": "y", "id": 1, "a.tags[]": "z"}`
+	expected := `{"a": {"tags": ["x", "y", "z"]}, "id": 1}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result.Output)
+	assert.Empty(t, result.Diagnostics)
+}
+
+func TestParse_success_dot_notation_index_on_an_object_is_a_key(t *testing.T) {
+	input := `{"a": {"x": 1}, "a.0": 2, "b.007": 3, "c.99999": 4}`
+	expected := `{"a": {"x": 1, "0": 2}, "b": {"007": 3}, "c": {"99999": 4}}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParser_Parse_success_dot_notation_array_conflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   DotConflictPolicy
+		input    string
+		expected string
+	}{
+		{"keep_dotted_key_into_array", DotConflictKeepDotted, `{"a": [1], "a.b": 2}`, `{"a": [1], "a.b": 2}`},
+		{"keep_dotted_append_onto_object", DotConflictKeepDotted, `{"a": {"x": 1}, "a[]": 2}`, `{"a": {"x": 1}, "a[]": 2}`},
+		{"keep_scalar_key_into_array", DotConflictKeepScalar, `{"a": [1], "a.b": 2}`, `{"a": {"_value": [1], "b": 2}}`},
+		{"keep_scalar_append_onto_object", DotConflictKeepScalar, `{"a": {"x": 1}, "a[]": 2}`, `{"a": [{"x": 1}, 2]}`},
+		{"keep_scalar_index_into_scalar", DotConflictKeepScalar, `{"a": 1, "a.0": 2}`, `{"a": {"_value": 1, "0": 2}}`},
+		{"overwrite_key_into_array", DotConflictOverwrite, `{"a": [1], "a.b": 2}`, `{"a": {"b": 2}}`},
+		{"overwrite_array_item", DotConflictOverwrite, `{"a.0.b": 1, "a[0]": 2}`, `{"a": [2]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Conflict: tt.policy}}
+			result, err := p.Parse(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParser_Parse_failure_dot_notation_array_conflict_error(t *testing.T) {
+	input := `{"items": [1, 2], "items.1.name": "x"}`
+
+	p := &Parser{DotNotation: DotNotationOptions{Conflict: DotConflictError}}
+	result, err := p.Parse(input)
+
+	assert.Equal(t, "", result)
+	conflictErr, ok := err.(*DotKeyConflictError)
+	assert.True(t, ok)
+	assert.Equal(t, "items[1]", conflictErr.Path)
+}
+
+func TestDotNotationOptions_splitPath(t *testing.T) {
+	tests := map[string][]pathSegment{
+		"a":            {{key: "a", index: -1}},
+		"a.b":          {{key: "a", index: -1}, {key: "b", index: -1}},
+		"items.0.name": {{key: "items", index: -1}, {key: "0", index: 0}, {key: "name", index: -1}},
+		"items[1].id":  {{key: "items", index: -1}, {key: "1", index: 1}, {key: "id", index: -1}},
+		"a.b[]":        {{key: "a", index: -1}, {key: "b", index: -1}, {index: -1, append: true}},
+		"m[0][1]":      {{key: "m", index: -1}, {key: "0", index: 0}, {key: "1", index: 1}},
+		`a["b"]`:       {{key: "a", index: -1}, {key: "b", index: -1}},
+		`a["0"]`:       {{key: "a", index: -1}, {key: "0", index: -1}},
+		"a[":           {{key: "a[", index: -1}},
+		"[]":           {{index: -1, append: true}},
+		"a..b":         {{key: "a", index: -1}, {key: "", index: -1}, {key: "b", index: -1}},
+	}
+	for key, expected := range tests {
+		t.Run(key, func(t *testing.T) {
+			assert.Equal(t, expected, DotNotationOptions{}.splitPath(key))
+		})
+	}
+}
+
+func TestParser_Parse_success_dot_notation_disabled(t *testing.T) {
+	input := `{"k8s.io/name": "web", "tags[]": "a", "tags[]": "b"}`
+	expected := `{"k8s.io/name": "web", "tags[]": "b"}`
+
+	p := &Parser{DotNotation: DotNotationOptions{Disabled: true}}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParser_Parse_success_dot_notation_custom_separator(t *testing.T) {
+	tests := []struct {
+		separator string
+		input     string
+		expected  string
+	}{
+		{"__", `{"APP__DB__HOST": "localhost", "APP__PORTS__0": 80, "example.com": true}`,
+			`{"APP": {"DB": {"HOST": "localhost"}, "PORTS": [80]}, "example.com": true}`},
+		{"/", `{"k8s.io/name": "web", "k8s.io/part-of": "shop"}`,
+			`{"k8s.io": {"name": "web", "part-of": "shop"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.separator, func(t *testing.T) {
+			p := &Parser{DotNotation: DotNotationOptions{Separator: tt.separator}}
+			result, err := p.Parse(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParse_success_dot_notation_escaped_separator(t *testing.T) {
+	input := `{"example\\.com.owner": "ops", "a\\.b": 1, "c\\[0]": 2}`
+	expected := `{"example.com": {"owner": "ops"}, "a.b": 1, "c[0]": 2}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParser_Parse_success_dot_notation_prefixes(t *testing.T) {
+	input := `{"labels": {"app.kubernetes.io/name": "web"}, "spring.profiles.active": "dev", "example.com": 1}`
+	expected := `{"labels": {"app.kubernetes.io/name": "web"}, "spring": {"profiles": {"active": "dev"}}, "example.com": 1}`
+
+	p := &Parser{DotNotation: DotNotationOptions{Prefixes: []string{"spring."}}}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, expected, result)
+}
+
+func TestDotNotationOptions_splitPath_custom_separator(t *testing.T) {
+	// Colons in keys don't make it through the repair, so this one is only checked here.
+	options := DotNotationOptions{Separator: ":"}
+	expected := []pathSegment{{key: "spring", index: -1}, {key: "a:b", index: -1}, {key: "0", index: 0}}
+
+	assert.Equal(t, expected, options.splitPath(`spring:a\:b[0]`))
+}
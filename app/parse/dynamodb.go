@@ -0,0 +1,274 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// DynamoDB attribute value type descriptors, as they show up in AWS CLI and SDK output.
+const (
+	dynamoString    = "S"
+	dynamoNumber    = "N"
+	dynamoBinary    = "B"
+	dynamoBool      = "BOOL"
+	dynamoNull      = "NULL"
+	dynamoMap       = "M"
+	dynamoList      = "L"
+	dynamoStringSet = "SS"
+	dynamoNumberSet = "NS"
+	dynamoBinarySet = "BS"
+)
+
+// DynamoDBOptions controls how we unwrap DynamoDB attribute values (`{"N": "5"}`) into plain JSON.
+type DynamoDBOptions struct {
+	// Disabled leaves attribute values as they are.  Ordinary data can look just like them, eg.
+	// `{"status": {"N": "5"}}`, so turn this off if yours does.
+	Disabled bool
+}
+
+// UnwrapDynamoDB walks the data looking for DynamoDB items (objects where every value is a typed attribute
+// value like `{"S": "1"}`) and replaces them with their natural JSON equivalent.  Anything that doesn't look
+// like an item is left as is, so this is safe to run over data that isn't from DynamoDB at all.
+func UnwrapDynamoDB(input interface{}) interface{} {
+	return unwrapDynamoDB(input, "", func(string) {})
+}
+
+// unwrapDynamoDB is UnwrapDynamoDB, calling unwrapped with the path of each item it unwraps.
+func unwrapDynamoDB(input interface{}, path string, unwrapped func(path string)) interface{} {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		if isDynamoDBItem(value) {
+			unwrapped(path)
+			result := make(map[string]interface{}, len(value))
+			for key, val := range value {
+				result[key] = unwrapAttributeValue(val)
+			}
+			return result
+		}
+		for key, val := range value {
+			if isContainer(val) {
+				value[key] = unwrapDynamoDB(val, joinPath(path, key), unwrapped)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, val := range value {
+			if isContainer(val) {
+				value[i] = unwrapDynamoDB(val, indexPath(path, i), unwrapped)
+			}
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// IsDynamoDBDocument reports whether the input is valid JSON that contains at least one DynamoDB item.
+func IsDynamoDBDocument(input string) bool {
+	var data interface{}
+	err := json.Unmarshal([]byte(input), &data)
+	if err != nil {
+		return false
+	}
+	return containsDynamoDBItem(data)
+}
+
+// WrapDynamoDB is the reverse of UnwrapDynamoDB.  A top level object becomes an item (each of its values
+// wrapped in an attribute value), anything else becomes a single attribute value.
+func WrapDynamoDB(input interface{}) interface{} {
+	dict, ok := input.(map[string]interface{})
+	if !ok {
+		return wrapAttributeValue(input)
+	}
+	result := make(map[string]interface{}, len(dict))
+	for key, val := range dict {
+		result[key] = wrapAttributeValue(val)
+	}
+	return result
+}
+
+// ToDynamoDB parses the input like `Parse` does, and then formats the result as a DynamoDB item.
+func ToDynamoDB(input string) (output string, err error) {
+	defer recoverPanic(&err)
+	// Input that was cut off is still converted, and we pass on that it's incomplete.
+	result, parseErr := Parse(input)
+	if parseErr != nil && !isTruncated(parseErr) {
+		return "", parseErr
+	}
+
+	var data interface{}
+	err = unmarshal(result, &data)
+	if err != nil {
+		return "", err
+	}
+
+	output, err = marshalIndent(WrapDynamoDB(data))
+	if err != nil {
+		return "", err
+	}
+	return output, parseErr
+}
+
+func containsDynamoDBItem(input interface{}) bool {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		if isDynamoDBItem(value) {
+			return true
+		}
+		for _, val := range value {
+			if containsDynamoDBItem(val) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, val := range value {
+			if containsDynamoDBItem(val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDynamoDBItem(input map[string]interface{}) bool {
+	if len(input) == 0 {
+		return false
+	}
+	for _, val := range input {
+		if !isAttributeValue(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAttributeValue is deliberately strict, both the descriptor and the type of what it wraps have to line up,
+// otherwise we'd end up mangling regular documents that happen to have an `S` or `M` key in them.
+func isAttributeValue(input interface{}) bool {
+	dict, ok := input.(map[string]interface{})
+	if !ok || len(dict) != 1 {
+		return false
+	}
+	for descriptor, val := range dict {
+		switch descriptor {
+		case dynamoString, dynamoBinary:
+			_, ok := val.(string)
+			return ok
+		case dynamoNumber:
+			// DynamoDB only hands out numbers JSON can hold as they are, so `"007"` is just a string.
+			str, ok := val.(string)
+			return ok && jsonNumberPattern.MatchString(str)
+		case dynamoBool:
+			_, ok := val.(bool)
+			return ok
+		case dynamoNull:
+			b, ok := val.(bool)
+			return ok && b
+		case dynamoMap:
+			nested, ok := val.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			for _, v := range nested {
+				if !isAttributeValue(v) {
+					return false
+				}
+			}
+			return true
+		case dynamoList:
+			arr, ok := val.([]interface{})
+			if !ok {
+				return false
+			}
+			for _, v := range arr {
+				if !isAttributeValue(v) {
+					return false
+				}
+			}
+			return true
+		case dynamoStringSet, dynamoBinarySet, dynamoNumberSet:
+			arr, ok := val.([]interface{})
+			if !ok {
+				return false
+			}
+			for _, v := range arr {
+				str, ok := v.(string)
+				if !ok || (descriptor == dynamoNumberSet && !jsonNumberPattern.MatchString(str)) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapAttributeValue expects to only be handed values that have passed `isAttributeValue`.
+func unwrapAttributeValue(input interface{}) interface{} {
+	dict := input.(map[string]interface{})
+	for descriptor, val := range dict {
+		switch descriptor {
+		case dynamoNumber:
+			// `json.Number` keeps the literal as is, so we don't lose precision on big numbers.
+			return json.Number(val.(string))
+		case dynamoNull:
+			return nil
+		case dynamoMap:
+			nested := val.(map[string]interface{})
+			result := make(map[string]interface{}, len(nested))
+			for key, v := range nested {
+				result[key] = unwrapAttributeValue(v)
+			}
+			return result
+		case dynamoList:
+			arr := val.([]interface{})
+			result := make([]interface{}, len(arr))
+			for i, v := range arr {
+				result[i] = unwrapAttributeValue(v)
+			}
+			return result
+		case dynamoNumberSet:
+			arr := val.([]interface{})
+			result := make([]interface{}, len(arr))
+			for i, v := range arr {
+				result[i] = json.Number(v.(string))
+			}
+			return result
+		default:
+			// Strings, binary (left base64 encoded), booleans, and the string/binary sets are already
+			// in their natural form.
+			return val
+		}
+	}
+	return nil
+}
+
+func wrapAttributeValue(input interface{}) interface{} {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, val := range value {
+			result[key] = wrapAttributeValue(val)
+		}
+		return map[string]interface{}{dynamoMap: result}
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, val := range value {
+			result[i] = wrapAttributeValue(val)
+		}
+		return map[string]interface{}{dynamoList: result}
+	case string:
+		return map[string]interface{}{dynamoString: value}
+	case json.Number:
+		return map[string]interface{}{dynamoNumber: value.String()}
+	case float64:
+		return map[string]interface{}{dynamoNumber: strconv.FormatFloat(value, 'f', -1, 64)}
+	case bool:
+		return map[string]interface{}{dynamoBool: value}
+	case nil:
+		return map[string]interface{}{dynamoNull: true}
+	default:
+		return map[string]interface{}{dynamoString: fmt.Sprint(value)}
+	}
+}
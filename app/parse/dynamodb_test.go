@@ -0,0 +1,181 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_unwraps_dynamodb_items(t *testing.T) {
+	input := `{"id": {"S": "1"}, "n": {"N": "1234567890123456789"}, "flag": {"BOOL": true}, "nothing": {"NULL": true}, "tags": {"SS": ["a", "b"]}, "scores": {"NS": ["1", "2.5"]}, "m": {"M": {"nested": {"L": [{"S": "x"}, {"N": "2"}]}}}}`
+	expected := `{
+    "flag": true,
+    "id": "1",
+    "m": {
+        "nested": [
+            "x",
+            2
+        ]
+    },
+    "n": 1234567890123456789,
+    "nothing": null,
+    "scores": [
+        1,
+        2.5
+    ],
+    "tags": [
+        "a",
+        "b"
+    ]
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_unwraps_dynamodb_scan_output(t *testing.T) {
+	input := `{"Items": [{"id": {"S": "1"}}, {"id": {"S": "2"}}], "Count": 2, "ScannedCount": 2}`
+	expected := `{
+    "Count": 2,
+    "Items": [
+        {
+            "id": "1"
+        },
+        {
+            "id": "2"
+        }
+    ],
+    "ScannedCount": 2
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_leaves_lookalike_dynamodb_data_alone(t *testing.T) {
+	input := `{"a": {"S": 1}, "b": {"N": "abc"}}`
+	expected := `{
+    "a": {
+        "S": 1
+    },
+    "b": {
+        "N": "abc"
+    }
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_leaves_numbers_json_cant_hold_alone(t *testing.T) {
+	for _, input := range []string{`{"a": {"N": "007"}}`, `{"a": {"NS": ["1", "+2"]}}`} {
+		result, err := Parse(input, WithCompact())
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, input, result)
+	}
+}
+
+func TestParser_ParseResult_success_reports_unwrapped_items(t *testing.T) {
+	input := `{"rows": [{"status": {"N": "5"}}]}`
+
+	result, err := NewParser(WithCompact()).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"rows":[{"status":5}]}`, result.Output)
+	assert.Equal(t, []Diagnostic{{Kind: DiagnosticDynamoDB, Path: "rows[0]", Start: -1, End: -1,
+		Message: "unwrapped DynamoDB attribute values into plain JSON"}}, result.Diagnostics)
+}
+
+func TestParse_success_without_dynamodb(t *testing.T) {
+	input := `{"status": {"N": "5"}}`
+
+	result, err := NewParser(WithCompact(), WithoutDynamoDB()).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"status":{"N":"5"}}`, result.Output)
+	assert.Empty(t, result.Diagnostics)
+}
+
+func TestToDynamoDB_success_wraps_plain_json(t *testing.T) {
+	input := `{"id": "abc", "n": 5, "flag": false, "nothing": null, "list": [1, "a"], "m": {"k": "v"}}`
+	expected := `{
+    "flag": {
+        "BOOL": false
+    },
+    "id": {
+        "S": "abc"
+    },
+    "list": {
+        "L": [
+            {
+                "N": "1"
+            },
+            {
+                "S": "a"
+            }
+        ]
+    },
+    "m": {
+        "M": {
+            "k": {
+                "S": "v"
+            }
+        }
+    },
+    "n": {
+        "N": "5"
+    },
+    "nothing": {
+        "NULL": true
+    }
+}`
+
+	result, err := ToDynamoDB(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestToDynamoDB_success_round_trips_dynamodb_input(t *testing.T) {
+	input := `{"id": {"S": "1"}, "n": {"N": "5"}}`
+	expected := `{
+    "id": {
+        "S": "1"
+    },
+    "n": {
+        "N": "5"
+    }
+}`
+
+	result, err := ToDynamoDB(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestToDynamoDB_success_wraps_truncated_input(t *testing.T) {
+	input := `{"id": 1, "tags": ["a"`
+	expected := `{
+    "id": {
+        "N": "1"
+    },
+    "tags": {
+        "L": [
+            {
+                "S": "a"
+            }
+        ]
+    }
+}`
+
+	result, err := ToDynamoDB(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, expected, result)
+}
@@ -0,0 +1,190 @@
+package parse
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// EmbeddedMode is what we do with input that has JSON embedded in other text, like a log line.
+type EmbeddedMode int
+
+const (
+	// EmbeddedExtract outputs only the documents we found, and drops the text around them.
+	EmbeddedExtract EmbeddedMode = iota
+	// EmbeddedInPlace outputs the original text, with each document pretty printed where it was found.
+	EmbeddedInPlace
+)
+
+// Region is a piece of JSON (or something close enough to it) found inside other text.
+type Region struct {
+	// Start and End are byte offsets into the input, such that `input[Start:End] == Text`.
+	Start int
+	End   int
+	Text  string
+}
+
+// FindEmbedded scans arbitrary text for balanced objects and arrays that look like JSON, and returns where
+// each one is.  Regions never overlap, nested structures are part of the region that holds them.
+func FindEmbedded(input string) (regions []Region) {
+	// There's no error to hand a PanicError back in, so if we hit a bug we say we found nothing, rather than take
+	// the caller down with us.
+	defer func() {
+		if recover() != nil {
+			regions = nil
+		}
+	}()
+	regions, _ = (&parseState{}).findEmbedded(input)
+	return regions
+}
+
+// findEmbedded is FindEmbedded, for when we're already recovering from panics ourselves, and might be cancelled.
+func (s *parseState) findEmbedded(input string) ([]Region, error) {
+	var regions []Region
+	closing := map[int]int{}
+	// We skip over the regions we find, so we can't count on landing on every multiple of the interval.
+	nextCheck := 0
+	for i := 0; i < len(input); i++ {
+		if i >= nextCheck {
+			if err := s.cancelled(); err != nil {
+				return nil, err
+			}
+			nextCheck = i + cancelCheckInterval
+		}
+		if !startsComplexDataStructure(rune(input[i])) {
+			continue
+		}
+		end := findClosingBracket(input, i, closing)
+		if end < 0 {
+			continue
+		}
+		text := input[i : end+1]
+		if !isJSONLike(text) {
+			// Things like `[INFO]` will land here, we still want to look inside them, so only skip the bracket.
+			continue
+		}
+		regions = append(regions, Region{Start: i, End: end + 1, Text: text})
+		i = end
+	}
+	return regions, nil
+}
+
+// findClosingBracket returns the index of the bracket that closes the one at `start`, or -1 if it's never closed,
+// or closed by the wrong kind of bracket.  Finding that out means finding the same for every bracket inside it, so
+// we keep those in `closing`, and never have to go over the same brackets twice.  Otherwise a line with a lot of
+// brackets that are never closed would take us quadratic time.
+func findClosingBracket(input string, start int, closing map[int]int) int {
+	if end, ok := closing[start]; ok {
+		return end
+	}
+	// The stack is where each open bracket is.
+	var stack []int
+	unclosed := func() int {
+		for _, open := range stack {
+			closing[open] = -1
+		}
+		return -1
+	}
+	inString := false
+	escaped := false
+	for i := start; i < len(input); i++ {
+		c := input[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, i)
+		case '}', ']':
+			open := stack[len(stack)-1]
+			if (input[open] == '{') != (c == '}') {
+				return unclosed()
+			}
+			closing[open] = i
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i
+			}
+		}
+	}
+	return unclosed()
+}
+
+// isJSONLike holds arrays to a higher standard than objects, there's a lot of `[bracketed]` text in logs.
+func isJSONLike(text string) bool {
+	if json.Valid([]byte(text)) {
+		return true
+	}
+	return strings.HasPrefix(text, "{") && strings.Contains(text, ":")
+}
+
+// isEmbeddedText decides whether the regions we found are sitting in other text, rather than being part of
+// the `key: {value}` style of input we repair.  The tell is what comes right before the region.
+func isEmbeddedText(input string, regions []Region) bool {
+	if len(regions) == 0 || strings.ContainsRune(`{["'`, rune(input[0])) {
+		return false
+	}
+	for _, region := range regions {
+		before := strings.TrimRight(input[:region.Start], " \t\r\n")
+		if before == "" || !strings.ContainsRune(":,[{", rune(before[len(before)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *parseState) parseEmbedded(input string, regions []Region) (string, error) {
+	if s.Embedded == EmbeddedExtract {
+		documents := make([]document, len(regions))
+		for i, region := range regions {
+			documents[i] = document{text: region.Text, offset: region.Start}
+		}
+		return s.parseDocuments(documents, input)
+	}
+
+	base := s.locate(input)
+	result := strings.Builder{}
+	var errs DocumentErrors
+	lines := NewLineCounter(input)
+	last := 0
+	for i, region := range regions {
+		if err := s.cancelled(); err != nil {
+			return "", err
+		}
+		result.WriteString(input[last:region.Start])
+		last = region.End
+
+		formatted, err := s.parseRegion(region.Text, base+region.Start)
+		if err != nil {
+			// Leave it as we found it, it's still useful to see in context.
+			errs = append(errs, &DocumentError{Index: i, Line: lines.Line(region.Start), Err: err})
+			result.WriteString(region.Text)
+			continue
+		}
+		result.WriteString(formatted)
+	}
+	result.WriteString(input[last:])
+
+	if len(errs) > 0 {
+		return result.String(), errs
+	}
+	return result.String(), nil
+}
+
+func (s *parseState) parseRegion(input string, offset int) (string, error) {
+	data, err := s.parseDocument(input, offset)
+	if err != nil {
+		return "", err
+	}
+	return s.marshal(data)
+}
@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindEmbedded_success_returns_regions_with_offsets(t *testing.T) {
+	input := `2026-10-01T12:00:00Z [INFO] request done {"status":200,"tags":["a"]} took=5ms {retry: 1}`
+
+	result := FindEmbedded(input)
+
+	assert.Equal(t, []Region{
+		{Start: 41, End: 68, Text: `{"status":200,"tags":["a"]}`},
+		{Start: 78, End: 88, Text: `{retry: 1}`},
+	}, result)
+	for _, region := range result {
+		assert.Equal(t, region.Text, input[region.Start:region.End])
+	}
+}
+
+func TestFindEmbedded_success_ignores_brackets_in_strings_and_unbalanced_text(t *testing.T) {
+	input := `oops ] { not closed {"msg": "a } in here"}`
+
+	result := FindEmbedded(input)
+
+	assert.Equal(t, []Region{{Start: 20, End: 42, Text: `{"msg": "a } in here"}`}}, result)
+}
+
+func TestParse_success_extracts_json_from_log_line(t *testing.T) {
+	input := `2026-10-01T12:00:00Z INFO request done {"status":200,"path":"/health"} took=5ms`
+	expected := `{
+    "path": "/health",
+    "status": 200
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_extracts_json_from_several_log_lines(t *testing.T) {
+	input := `12:00:00 INFO start {"id": 1}
+12:00:01 INFO stop {"id": 2}`
+	expected := `[
+    {
+        "id": 1
+    },
+    {
+        "id": 2
+    }
+]`
+
+	p := &Parser{DocumentFormat: DocumentsArray}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_success_pretty_prints_embedded_json_in_place(t *testing.T) {
+	input := `12:00:00 INFO done {"status":200} took=5ms`
+	expected := `12:00:00 INFO done {
+    "status": 200
+} took=5ms`
+
+	p := &Parser{Embedded: EmbeddedInPlace}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_key_with_nested_value_is_not_embedded(t *testing.T) {
+	input := `key: value, dict: {nested: 1}`
+	expected := `{
+    "dict": {
+        "nested": 1
+    },
+    "key": "value"
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestFindEmbedded_success_unclosed_brackets(t *testing.T) {
+	input := `[a [b {"x": [1]} [c {"y": 2}`
+
+	regions := FindEmbedded(input)
+
+	assert.Equal(t, []Region{
+		{Start: 6, End: 16, Text: `{"x": [1]}`},
+		{Start: 20, End: 28, Text: `{"y": 2}`},
+	}, regions)
+}
+
+func TestFindClosingBracket_remembers_inner_brackets(t *testing.T) {
+	input := `{"a": [1, {"b": "]"}], "c": [2} [3]`
+	closing := map[int]int{}
+
+	assert.Equal(t, -1, findClosingBracket(input, 0, closing))
+	for start, end := range closing {
+		// What we remembered is the same as we'd have found starting from there.
+		assert.Equal(t, findClosingBracket(input, start, map[int]int{}), end, "bracket at %d", start)
+	}
+	assert.Equal(t, 20, closing[6])
+	assert.Equal(t, -1, closing[28])
+	assert.Equal(t, 34, findClosingBracket(input, 32, closing))
+}
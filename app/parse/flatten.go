@@ -0,0 +1,182 @@
+package parse
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IndexStyle is how Flatten writes array indexes into keys.
+type IndexStyle int
+
+const (
+	// IndexDotted writes indexes like any other key, eg. `items.0.name`.
+	IndexDotted IndexStyle = iota
+	// IndexBrackets writes indexes in brackets, eg. `items[0].name`.
+	IndexBrackets
+)
+
+// FlattenFormat is what Flatten writes out.
+type FlattenFormat int
+
+const (
+	// FlattenObject writes a single level JSON object.
+	FlattenObject FlattenFormat = iota
+	// FlattenLines writes a `KEY=value` line for each key, like a `.env` or `.properties` file.
+	FlattenLines
+)
+
+// FlattenOptions controls how Flatten writes keys out.
+type FlattenOptions struct {
+	// Separator goes between each key, eg. `__` for environment variables.  It defaults to `.`.
+	Separator string
+	Indexes   IndexStyle
+	Format    FlattenFormat
+}
+
+func (o FlattenOptions) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+// Flatten parses the input like `Parse` does, and then turns it into a single level object with dot notation
+// keys, eg. `{"a": {"b": [1]}}` is `{"a.b.0": 1}`.  It's the opposite of how we expand dot notation keys, so
+// parsing the output again, with the same separator, gives you back the original.  Keys that get in the way of
+// that are escaped, eg. a `.` in a key is written as `\.`, and an object key that's a number as `["0"]`.
+// A top level array comes back as an object though, and empty objects and arrays are kept as they are.
+func Flatten(input string, flatten FlattenOptions, options ...Option) (string, error) {
+	return NewParser(options...).Flatten(input, flatten)
+}
+
+// Flatten parses the input with the Parser's settings, and writes the object out the way it's set up to.  See
+// `Flatten` for what it does with it.
+func (p *Parser) Flatten(input string, options FlattenOptions) (output string, err error) {
+	defer recoverPanic(&err)
+	// Input that was cut off still gets flattened, and we pass on that it's incomplete.
+	result, parseErr := p.Parse(input)
+	if parseErr != nil && !isTruncated(parseErr) {
+		return "", parseErr
+	}
+
+	var data interface{}
+	err = unmarshal(result, &data)
+	if err != nil {
+		return "", err
+	}
+
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		// There's nothing to flatten.
+		return result, parseErr
+	}
+
+	flat := map[string]interface{}{}
+	options.flatten("", data, flat)
+	if options.Format == FlattenLines {
+		output, err = flattenLines(flat)
+	} else {
+		output, err = (&parseState{Parser: p}).marshal(flat)
+	}
+	if err != nil {
+		return "", err
+	}
+	return output, parseErr
+}
+
+func (o FlattenOptions) flatten(prefix string, value interface{}, result map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			result[prefix] = v
+			return
+		}
+		for key, val := range v {
+			o.flatten(o.objectKey(prefix, key), val, result)
+		}
+	case []interface{}:
+		if len(v) == 0 && prefix != "" {
+			result[prefix] = v
+			return
+		}
+		for i, val := range v {
+			o.flatten(o.indexKey(prefix, i), val, result)
+		}
+	default:
+		result[prefix] = value
+	}
+}
+
+func (o FlattenOptions) objectKey(prefix, key string) string {
+	if newPathSegment(key).index >= 0 {
+		// It'd be expanded back into an array otherwise.
+		return prefix + `["` + key + `"]`
+	}
+
+	escaped := strings.ReplaceAll(key, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, o.separator(), `\`+o.separator())
+	escaped = strings.ReplaceAll(escaped, "[", `\[`)
+	if prefix == "" {
+		return escaped
+	}
+	return prefix + o.separator() + escaped
+}
+
+func (o FlattenOptions) indexKey(prefix string, index int) string {
+	if o.Indexes == IndexBrackets {
+		return prefix + "[" + strconv.Itoa(index) + "]"
+	}
+	if prefix == "" {
+		return strconv.Itoa(index)
+	}
+	return prefix + o.separator() + strconv.Itoa(index)
+}
+
+// flattenLines writes a `KEY=value` line for each key, sorted by key.  Keys and strings are written as is, unless
+// they'd be read back differently, in which case they're quoted.  Nulls are left empty.
+func flattenLines(flat map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, err := lineValue(flat[key])
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, lineKey(key)+"="+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// lineKey quotes a key that would otherwise run into its value, or onto another line, like one with a `=` or
+// spaces in it.
+func lineKey(key string) string {
+	if key == "" || strings.ContainsAny(key, "= \t\"'#\n\r") {
+		quoted, _ := json.Marshal(key)
+		return string(quoted)
+	}
+	return key
+}
+
+func lineValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		if v == "" || strings.TrimSpace(v) != v || strings.ContainsAny(v, "\"'#\n\r") {
+			quoted, err := json.Marshal(v)
+			return string(quoted), err
+		}
+		return v, nil
+	default:
+		result, err := json.Marshal(v)
+		return string(result), err
+	}
+}
@@ -0,0 +1,138 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFlatten_success_dotted_keys(t *testing.T) {
+	input := `{"a": {"b": 1, "c": [true, {"d": null}]}, "e": "x"}`
+	expected := `{
+    "a.b": 1,
+    "a.c.0": true,
+    "a.c.1.d": null,
+    "e": "x"
+}`
+
+	result, err := Flatten(input, FlattenOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestFlatten_success_bracket_indexes(t *testing.T) {
+	input := `{"items": [{"id": 1}, {"id": 2}], "tags": ["x", "y"]}`
+	expected := `{
+    "items[0].id": 1,
+    "items[1].id": 2,
+    "tags[0]": "x",
+    "tags[1]": "y"
+}`
+
+	result, err := Flatten(input, FlattenOptions{Indexes: IndexBrackets})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestFlatten_success_lines(t *testing.T) {
+	input := `{"APP": {"DB": {"HOST": "localhost", "PORT": 5432}, "NAME": "app#1", "DEBUG": false, "EXTRA": null}}`
+	expected := `APP__DB__HOST=localhost
+APP__DB__PORT=5432
+APP__DEBUG=false
+APP__EXTRA=
+APP__NAME="app#1"`
+
+	result, err := Flatten(input, FlattenOptions{Separator: "__", Format: FlattenLines})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestFlatten_success_lines_quote_keys(t *testing.T) {
+	input := `{"a=b": 1, "with space": 2, "multi\nline": 3, "": 4, "plain": 5}`
+	expected := `""=4
+"a=b"=1
+"multi\nline"=3
+plain=5
+"with space"=2`
+
+	result, err := Flatten(input, FlattenOptions{Format: FlattenLines})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Flatten_success_uses_parser_options(t *testing.T) {
+	input := "{a: {b: None, c: TRUE}}"
+
+	result, err := NewParser(WithCompact(), WithLiterals(LiteralOptions{Dialect: DialectPython})).Flatten(input,
+		FlattenOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a.b":null,"a.c":"TRUE"}`, result)
+}
+
+func TestFlatten_success_flattens_truncated_input(t *testing.T) {
+	input := `{"a": {"b": 1, "c": [2`
+
+	result, err := Flatten(input, FlattenOptions{}, WithCompact())
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, `{"a.b":1,"a.c.0":2}`, result)
+}
+
+func TestFlattenOptions_flatten_success_escapes_keys(t *testing.T) {
+	// Numbers as keys don't make it through the repair, so we skip it here.
+	input := map[string]interface{}{
+		"example.com": map[string]interface{}{
+			"0":     "zero",
+			"a[b]":  1,
+			`c\d`:   2,
+			"empty": map[string]interface{}{},
+			"none":  []interface{}{},
+		},
+	}
+	expected := map[string]interface{}{
+		`example\.com["0"]`:  "zero",
+		`example\.com.a\[b]`: 1,
+		`example\.com.c\\d`:  2,
+		`example\.com.empty`: map[string]interface{}{},
+		`example\.com.none`:  []interface{}{},
+	}
+
+	result := map[string]interface{}{}
+	FlattenOptions{}.flatten("", input, result)
+
+	assert.Equal(t, expected, result)
+}
+
+func TestFlattenOptions_flatten_success_round_trips(t *testing.T) {
+	inputs := []string{
+		`{"a": {"b": 1, "c": [true, null, {"d": "x"}]}, "e": "y"}`,
+		`{"example.com": {"owner": "ops"}, "k8s.io/name": "web", "APP__NAME": "x"}`,
+		`{"a": {"0": "zero", "1": ["one"]}, "b[c]": {"d\\e": 2}, "f[]": 3}`,
+		`{"a": {"empty": {}, "none": [], "nested": [[1, 2], [3]]}}`,
+	}
+	for _, input := range inputs {
+		for _, options := range []FlattenOptions{{}, {Indexes: IndexBrackets}, {Separator: "__"}} {
+			t.Run(input, func(t *testing.T) {
+				var data interface{}
+				assert.Nil(t, unmarshal(input, &data))
+				flat := map[string]interface{}{}
+				options.flatten("", data, flat)
+				flatJSON, err := marshalIndent(flat)
+				assert.Nil(t, err)
+
+				// Straight to the dot notation expansion, since the repair isn't what we're testing.
+				s := &parseState{Parser: &Parser{DotNotation: DotNotationOptions{Separator: options.Separator}}}
+				result, err := s.recursiveUnmarshal(flatJSON, 0)
+
+				assert.Nil(t, err)
+				resultJSON, err := marshalIndent(result)
+				assert.Nil(t, err)
+				assert.JSONEq(t, input, resultJSON)
+			})
+		}
+	}
+}
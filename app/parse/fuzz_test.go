@@ -0,0 +1,145 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Run with `go test -fuzz FuzzParse`.  Anything it finds is saved under testdata/fuzz, and run by `go test` from
+// then on.
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"", "{}", "[]", ":", ",", "{:}", `""`, "''", `{"": 1}`, "1", "-0.5e10", "null",
+		`{"a": "hello world", "b": "42", "c": [1, {"d": null}]}`,
+		`[{"a": 1}, {"a": 2}]`,
+		`{'a': None, b: True}`,
+		"a: 1\nb: hello\nworld\nc.d: [1, 2]",
+		`"{\"a\": 1}"`,
+		"```json\n{\"a\": 1}\n```",
+		"INFO request {\"id\": 1} done",
+		"{\"a\": 1}\n{\"a\": 2}",
+		`{"a": [1, 2`,
+		`{"a": {"S": "x"}}`,
+		"a..b: 1",
+		"a[]: 1\na[]: 2",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	// Everything comes out as a single document, so there's always one JSON value to check.
+	p := NewParser(WithDocumentFormat(DocumentsArray), WithCompact())
+	// Turn off everything that changes valid JSON on purpose, so what's left should give it back as it was.
+	roundTrip := NewParser(WithCompact(), WithoutDotNotation(), WithoutStringDecoding(), WithoutDynamoDB(),
+		WithJWT(JWTOptions{Disabled: true}))
+
+	f.Fuzz(func(t *testing.T, input string) {
+		output, err := p.Parse(input)
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("panicked on %q: %v\n%s", input, panicErr.Value, panicErr.Stack)
+		}
+		if output != "" && !json.Valid([]byte(output)) {
+			t.Fatalf("invalid JSON out of %q: %s", input, output)
+		}
+
+		if !json.Valid([]byte(input)) {
+			return
+		}
+		output, err = roundTrip.Parse(input)
+		if err != nil {
+			t.Fatalf("failed on valid JSON %q: %v", input, err)
+		}
+		if !reflect.DeepEqual(decodeJSON(t, input), decodeJSON(t, output)) {
+			t.Fatalf("valid JSON %q came back as %s", input, output)
+		}
+	})
+}
+
+// FuzzEntryPoints checks the rest of what we export never panics either, whatever it's given.
+func FuzzEntryPoints(f *testing.F) {
+	for _, seed := range []string{"", "{}", `{"a": {"b": [1, "x"]}}`, "INFO {\"id\": 1} done [2", `{"a": {"N": "1"}}`, "a: 1"} {
+		f.Add(seed)
+	}
+	p := NewParser()
+	f.Fuzz(func(t *testing.T, input string) {
+		_, err := RecursiveUnmarshal(input)
+		checkPanic(t, "RecursiveUnmarshal", input, err)
+		for _, format := range []FlattenFormat{FlattenObject, FlattenLines} {
+			_, err = Flatten(input, FlattenOptions{Format: format})
+			checkPanic(t, "Flatten", input, err)
+		}
+		_, err = ToDynamoDB(input)
+		checkPanic(t, "ToDynamoDB", input, err)
+
+		for _, region := range FindEmbedded(input) {
+			if input[region.Start:region.End] != region.Text {
+				t.Fatalf("FindEmbedded(%q) found %q at %d:%d", input, region.Text, region.Start, region.End)
+			}
+		}
+
+		data, err := RecursiveUnmarshal(input)
+		if err != nil {
+			return
+		}
+		err = p.NewEncoder(&strings.Builder{}).Encode(data)
+		checkPanic(t, "Encode", input, err)
+	})
+}
+
+// checkPanic fails the test if the error is from a panic.
+func checkPanic(t *testing.T, name, input string, err error) {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		t.Fatalf("%s panicked on %q: %v\n%s", name, input, panicErr.Value, panicErr.Stack)
+	}
+}
+
+// FuzzValidate checks Validate agrees with encoding/json on what's valid.  encoding/json lets invalid UTF-8
+// through, where RFC 8259 doesn't, so that's left out.
+func FuzzValidate(f *testing.F) {
+	for _, seed := range []string{"", "{}", `{"a": [1, 2.5e-3, true, null, "é"]}`, "[1,]", "01", `"\ud800"`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		err := Validate(input, StrictOptions{})
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("panicked on %q: %v\n%s", input, panicErr.Value, panicErr.Stack)
+		}
+		if utf8.ValidString(input) && (err == nil) != json.Valid([]byte(input)) {
+			t.Fatalf("Validate(%q) = %v, but json.Valid says %v", input, err, json.Valid([]byte(input)))
+		}
+	})
+}
+
+func decodeJSON(t *testing.T, input string) interface{} {
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("couldn't decode %q: %v", input, err)
+	}
+	return data
+}
+
+// FuzzDecode checks decoding valid JSON gives the same as encoding/json, and invalid JSON fails the same.
+func FuzzDecode(f *testing.F) {
+	for _, seed := range []string{"", "{}", `{"a": [1, -2.5e-3, true, null, "é\n"]}`, `{"a": 1, "a": 2}`, "01", `"\ud800"`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		s := &parseState{Parser: &Parser{}, input: input}
+		data, err := s.decode(input, 0, "")
+		if (err == nil) != json.Valid([]byte(input)) {
+			t.Fatalf("decode(%q) = %v, but json.Valid says %v", input, err, json.Valid([]byte(input)))
+		}
+		if err == nil && !reflect.DeepEqual(decodeJSON(t, input), data) {
+			t.Fatalf("decode(%q) = %#v", input, data)
+		}
+	})
+}
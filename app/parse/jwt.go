@@ -0,0 +1,342 @@
+package parse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var jwtPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,}\.[A-Za-z0-9_-]{2,}\.[A-Za-z0-9_-]*$`)
+
+// What we say about a token's signature, under the `signature` key.
+const (
+	jwtNotVerified = "not verified"
+	jwtVerified    = "verified"
+)
+
+// The claims that hold times, which we write out in a form people can read.
+var jwtTimeClaims = []string{"exp", "iat", "nbf"}
+
+// JWTOptions controls how we decode JSON Web Tokens found in string values, or as the input as a whole.  A token
+// becomes an object with its `header` and `payload`, the `times` in the payload written out in UTC, and whether the
+// `signature` was verified.  It isn't, unless you give us a key to do it with.
+type JWTOptions struct {
+	// Disabled leaves tokens as they are.
+	Disabled bool
+	// Secret is the key for HMAC signatures (`HS256`, `HS384` and `HS512`).
+	Secret []byte
+	// PublicKey is a PEM encoded public key, or certificate, for RSA, ECDSA and EdDSA signatures.
+	PublicKey []byte
+	// JWKS is a JSON Web Key Set, like an identity provider's `jwks.json`.  If the token has a `kid`, only the key
+	// with the same `kid` is used.
+	JWKS []byte
+}
+
+// jwk is a single JSON Web Key, out of a JWKS.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// decodeJWT turns a token into an object with its header and payload, or returns false if it isn't one.  A
+// `Bearer ` in front, like in an `Authorization` header, is fine.
+func (s *parseState) decodeJWT(value, path string) (interface{}, bool, error) {
+	if s.JWT.Disabled {
+		return nil, false, nil
+	}
+	token := strings.TrimSpace(value)
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	// Counting the dots is much cheaper than the regex, and rules out nearly every string that isn't a token.
+	if strings.Count(token, ".") != 2 || !jwtPattern.MatchString(token) {
+		return nil, false, nil
+	}
+
+	parts := strings.Split(token, ".")
+	header, ok := s.decodeJWTPart(parts[0], path)
+	if !ok {
+		return nil, false, nil
+	}
+	// Plenty of things have three parts with dots in between, but only a token has a header with an `alg`.
+	alg, ok := header["alg"].(string)
+	if !ok {
+		return nil, false, nil
+	}
+	payload, ok := s.decodeJWTPart(parts[1], path)
+	if !ok {
+		return nil, false, nil
+	}
+	processed, err := s.processRecursively(payload, joinPath(path, "payload"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := map[string]interface{}{}
+	s.setKey(result, "header", header)
+	s.setKey(result, "payload", processed)
+	if times := jwtTimes(payload); len(times) > 0 {
+		s.setKey(result, "times", times)
+	}
+	signature := s.verifyJWT(alg, header, parts)
+	s.setKey(result, "signature", signature)
+
+	s.report(DiagnosticJWT, path, -1, -1, "decoded a JWT, its signature is %s", signature)
+	return result, true, nil
+}
+
+func (s *parseState) decodeJWTPart(part, path string) (map[string]interface{}, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return nil, false
+	}
+	data, err := s.decode(string(decoded), -1, path)
+	if err != nil {
+		return nil, false
+	}
+	object, ok := data.(map[string]interface{})
+	return object, ok
+}
+
+// jwtTimes writes out the times in the payload in UTC, and calls out a token that's expired or not valid yet.
+func jwtTimes(payload map[string]interface{}) map[string]interface{} {
+	times := map[string]interface{}{}
+	now := time.Now()
+	for _, claim := range jwtTimeClaims {
+		number, ok := payload[claim].(json.Number)
+		if !ok {
+			continue
+		}
+		seconds, err := number.Float64()
+		if err != nil {
+			continue
+		}
+		at := time.Unix(int64(seconds), 0).UTC()
+		readable := at.Format(time.RFC3339)
+		switch {
+		case claim == "exp" && at.Before(now):
+			readable += " (expired)"
+		case claim == "nbf" && at.After(now):
+			readable += " (not valid yet)"
+		}
+		times[claim] = readable
+	}
+	return times
+}
+
+// verifyJWT checks the signature against whatever keys we were given, and says how it went.
+func (s *parseState) verifyJWT(alg string, header map[string]interface{}, parts []string) string {
+	options := s.JWT
+	if options.Secret == nil && options.PublicKey == nil && options.JWKS == nil {
+		return jwtNotVerified
+	}
+	if alg == "none" {
+		return jwtNotVerified + ", the token isn't signed"
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return "invalid, the signature isn't base64"
+	}
+
+	kid, _ := header["kid"].(string)
+	keys, err := options.keys(kid)
+	if err != nil {
+		return fmt.Sprintf("%s, %s", jwtNotVerified, err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	tried := false
+	for _, key := range keys {
+		ok, compatible := verifySignature(alg, key, signed, signature)
+		if ok {
+			return jwtVerified
+		}
+		tried = tried || compatible
+	}
+	if !tried {
+		return fmt.Sprintf("%s, there's no key for %s", jwtNotVerified, alg)
+	}
+	return "invalid, the signature doesn't match"
+}
+
+// keys is every key we were given that the token could have been signed with.
+func (o JWTOptions) keys(kid string) ([]interface{}, error) {
+	var keys []interface{}
+	if o.Secret != nil {
+		keys = append(keys, o.Secret)
+	}
+
+	rest := o.PublicKey
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := parsePEMKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if o.PublicKey != nil && len(keys) == 0 {
+		return nil, errors.New("couldn't find a PEM block in the public key")
+	}
+
+	if o.JWKS != nil {
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		err := json.Unmarshal(o.JWKS, &set)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the JWKS: %w", err)
+		}
+		for _, k := range set.Keys {
+			if kid != "" && k.Kid != "" && k.Kid != kid {
+				continue
+			}
+			key, err := k.key()
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read key %q in the JWKS: %w", k.Kid, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func parsePEMKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return certificate.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+func (k jwk) key() (interface{}, error) {
+	decode := func(value string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	}
+
+	switch k.Kty {
+	case "oct":
+		return decode(k.K)
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks the signature with the key.  It also returns whether the key is the right kind for the
+// algorithm at all.
+func verifySignature(alg string, key interface{}, signed, signature []byte) (bool, bool) {
+	if alg == "EdDSA" {
+		public, ok := key.(ed25519.PublicKey)
+		// Verify panics on a key that's the wrong size.
+		ok = ok && len(public) == ed25519.PublicKeySize
+		return ok && ed25519.Verify(public, signed, signature), ok
+	}
+
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	if len(alg) != 5 {
+		return false, false
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return false, false
+	}
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false, false
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature), true
+	case "RS":
+		public, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(public, hash, digest, signature) == nil, ok
+	case "PS":
+		public, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPSS(public, hash, digest, signature, nil) == nil, ok
+	case "ES":
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false, false
+		}
+		// The signature is `r` and `s` back to back, each padded out to the size of the curve.
+		size := (public.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false, true
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		sig := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(public, digest, r, sig), true
+	default:
+		return false, false
+	}
+}
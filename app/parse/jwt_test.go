@@ -0,0 +1,178 @@
+package parse
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testJWTHeader = `{"alg":"HS256","typ":"JWT"}`
+const testJWTPayload = `{"sub":"1234","name":"Jane","iat":1516239022,"exp":1516242622}`
+
+func testJWT(header, payload string, sign func([]byte) []byte) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hmacSigner(secret string) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func TestParser_ParseResult_success_decodes_top_level_jwt(t *testing.T) {
+	input := testJWT(testJWTHeader, testJWTPayload, hmacSigner("secret"))
+	expected := `{
+    "header": {
+        "alg": "HS256",
+        "typ": "JWT"
+    },
+    "payload": {
+        "exp": 1516242622,
+        "iat": 1516239022,
+        "name": "Jane",
+        "sub": "1234"
+    },
+    "signature": "not verified",
+    "times": {
+        "exp": "2018-01-18T02:30:22Z (expired)",
+        "iat": "2018-01-18T01:30:22Z"
+    }
+}`
+
+	p := &Parser{}
+	result, err := p.ParseResult("Bearer " + input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.Output)
+	assert.Equal(t, []Diagnostic{{
+		Kind:    DiagnosticJWT,
+		Start:   -1,
+		End:     -1,
+		Message: "decoded a JWT, its signature is not verified",
+	}}, result.Diagnostics)
+}
+
+func TestParseState_processValue_success_decodes_jwt_in_a_value(t *testing.T) {
+	input := `{"headers": {"Authorization": "Bearer ` + testJWT(testJWTHeader, `{"sub":"1234"}`, hmacSigner("x")) + `"}}`
+	expected := `{
+    "headers": {
+        "Authorization": {
+            "header": {
+                "alg": "HS256",
+                "typ": "JWT"
+            },
+            "payload": {
+                "sub": "1234"
+            },
+            "signature": "not verified"
+        }
+    }
+}`
+
+	result, diagnostics := unwrapped(t, &Parser{}, input)
+
+	assert.Equal(t, expected, result)
+	assert.Equal(t, []Diagnostic{{
+		Kind:    DiagnosticJWT,
+		Path:    "headers.Authorization",
+		Start:   -1,
+		End:     -1,
+		Message: "decoded a JWT, its signature is not verified",
+	}}, diagnostics)
+}
+
+func TestParse_success_leaves_things_that_only_look_like_jwts(t *testing.T) {
+	input := `{"host": "api.example.com", "version": "v1.2.3", "fake": "` +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT"}`)) + `.eyJhIjoxfQ.c2ln"}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, result.Output, `"header"`)
+	assert.Empty(t, result.Diagnostics)
+}
+
+func TestParser_Parse_success_jwt_disabled(t *testing.T) {
+	token := testJWT(testJWTHeader, testJWTPayload, hmacSigner("secret"))
+	input := `{"token": "` + token + `"}`
+
+	p := &Parser{JWT: JWTOptions{Disabled: true}}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, input, result)
+}
+
+func TestParser_ParseResult_success_verifies_jwt_signatures(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ecPublic, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err)
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPublic})
+	ecSigner := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		assert.Nil(t, err)
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	}
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	jwks := []byte(`{"keys": [
+		{"kty": "oct", "kid": "shared", "k": "` + base64.RawURLEncoding.EncodeToString([]byte("jwks-secret")) + `"},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "` + base64.RawURLEncoding.EncodeToString(edPublic) + `"}
+	]}`)
+	edSigner := func(signed []byte) []byte {
+		return ed25519.Sign(edPrivate, signed)
+	}
+
+	tests := []struct {
+		name     string
+		options  JWTOptions
+		header   string
+		sign     func([]byte) []byte
+		expected string
+	}{
+		{"hmac", JWTOptions{Secret: []byte("secret")}, testJWTHeader, hmacSigner("secret"), "verified"},
+		{"hmac_wrong_secret", JWTOptions{Secret: []byte("secret")}, testJWTHeader, hmacSigner("other"),
+			"invalid, the signature doesn't match"},
+		{"ecdsa_pem", JWTOptions{PublicKey: ecPEM}, `{"alg":"ES256"}`, ecSigner, "verified"},
+		{"ecdsa_pem_tampered", JWTOptions{PublicKey: ecPEM}, `{"alg":"ES256"}`,
+			func(signed []byte) []byte { return ecSigner(append(signed, 'x')) }, "invalid, the signature doesn't match"},
+		{"jwks_hmac_by_kid", JWTOptions{JWKS: jwks}, `{"alg":"HS256","kid":"shared"}`, hmacSigner("jwks-secret"),
+			"verified"},
+		{"jwks_eddsa_by_kid", JWTOptions{JWKS: jwks}, `{"alg":"EdDSA","kid":"ed"}`, edSigner, "verified"},
+		{"no_key_for_alg", JWTOptions{PublicKey: ecPEM}, `{"alg":"RS256"}`, hmacSigner("x"),
+			"not verified, there's no key for RS256"},
+		{"unsigned", JWTOptions{Secret: []byte("secret")}, `{"alg":"none"}`,
+			func([]byte) []byte { return nil }, "not verified, the token isn't signed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `{"token": "` + testJWT(tt.header, `{"sub":"1"}`, tt.sign) + `"}`
+
+			p := &Parser{JWT: tt.options}
+			result, err := p.ParseResult(input)
+
+			assert.Nil(t, err)
+			assert.Contains(t, result.Output, `"signature": "`+tt.expected+`"`)
+			assert.Equal(t, "decoded a JWT, its signature is "+tt.expected, result.Diagnostics[0].Message)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package parse
+
+import (
+	"fmt"
+)
+
+// defaultMaxDepth is as deep as we'll let objects and arrays nest, unless told otherwise.  The repair follows
+// each level down, so without a limit a paste of a few hundred thousand `[` would run it out of stack.
+const defaultMaxDepth = 10000
+
+// cancelCheckInterval is how many characters we get through in the repair between checks that we haven't been
+// cancelled.
+const cancelCheckInterval = 4096
+
+// LimitOptions bounds how much work a single parse is allowed to do.
+type LimitOptions struct {
+	// MaxSize is the most bytes of input we'll take on.  It defaults to no limit.
+	MaxSize int
+	// MaxDepth is how deep objects and arrays can nest.  It defaults to 10000.  Brackets in strings count too,
+	// as the repair can't always tell they're in one.
+	MaxDepth int
+}
+
+func (o LimitOptions) maxDepth() int {
+	if o.MaxDepth <= 0 {
+		return defaultMaxDepth
+	}
+	return o.MaxDepth
+}
+
+// LimitKind is which limit the input went over.
+type LimitKind string
+
+const (
+	// LimitSize is for input bigger than MaxSize.
+	LimitSize LimitKind = "size"
+	// LimitDepth is for input nested deeper than MaxDepth.
+	LimitDepth LimitKind = "depth"
+)
+
+// LimitError is returned for input that goes over one of the LimitOptions, before we do any work on it.
+type LimitError struct {
+	Kind LimitKind
+	// Limit is the limit that was gone over, and Size how big the input was, or how deep it went before we gave up.
+	Limit int
+	Size  int
+	// Offset is the byte offset into the input where it went over the limit.
+	Offset int
+}
+
+func (e *LimitError) Error() string {
+	if e.Kind == LimitSize {
+		return fmt.Sprintf("input is %d bytes, over the limit of %d bytes", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("input is nested more than %d levels deep at offset %d", e.Limit, e.Offset)
+}
+
+// checkLimits makes sure the input is within the limits, which is cheap to do compared to repairing it.
+func (s *parseState) checkLimits(input string) error {
+	if s.Limits.MaxSize > 0 && len(input) > s.Limits.MaxSize {
+		return &LimitError{Kind: LimitSize, Limit: s.Limits.MaxSize, Size: len(input), Offset: s.Limits.MaxSize}
+	}
+
+	maxDepth := s.Limits.maxDepth()
+	depth := 0
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return &LimitError{Kind: LimitDepth, Limit: maxDepth, Size: depth, Offset: i}
+			}
+		case '}', ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// cancelled returns an error once the context we're parsing under has been cancelled, or run out of time.
+func (s *parseState) cancelled() error {
+	if s.ctx == nil {
+		return nil
+	}
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("parsing stopped: %w", err)
+	}
+	return nil
+}
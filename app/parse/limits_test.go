@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContext_success(t *testing.T) {
+	output, err := ParseContext(context.Background(), `{"a": 1}`, WithCompact())
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":1}`, output)
+}
+
+func TestParseContext_failure_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParseContext(ctx, `{"a": 1}`)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParseContext_failure_past_deadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	// Several documents, so we'd have plenty of chances to notice.
+	input := strings.Repeat("{a: 1}\n", 100)
+
+	_, err := NewParser().ParseContext(ctx, input)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "parsing stopped")
+}
+
+func TestParseContext_failure_cancelled_partway_through(t *testing.T) {
+	s := &parseState{Parser: &Parser{}, input: "a: 1"}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	cancel()
+
+	_, _, err := s.correctInvalidFormatting(true, []rune(strings.Repeat("a: 1\n", 1000)), 0)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParseContext_failure_cancelled_before_the_repair(t *testing.T) {
+	s := &parseState{Parser: &Parser{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	cancel()
+	truncated := strings.Repeat(`{"a": [1, `, 1000)
+	input := "> Here you go\n> " + truncated
+
+	_, _, err := s.closeTruncated(truncated)
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, _, err = s.stripMarkdown(input)
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = s.findEmbedded(input)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParse_failure_over_size_limit(t *testing.T) {
+	_, err := Parse(`{"a": "hello"}`, WithLimits(LimitOptions{MaxSize: 10}))
+
+	var limitErr *LimitError
+	assert.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, LimitSize, limitErr.Kind)
+	assert.Equal(t, 14, limitErr.Size)
+	assert.Equal(t, "input is 14 bytes, over the limit of 10 bytes", err.Error())
+
+	_, err = Parse(`{"a": 1}`, WithLimits(LimitOptions{MaxSize: 10}))
+
+	assert.Nil(t, err)
+}
+
+func TestParse_failure_over_depth_limit(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		limits LimitOptions
+		offset int
+	}{
+		{"default", strings.Repeat("[", defaultMaxDepth+1), LimitOptions{}, defaultMaxDepth},
+		{"set", `{"a": {"b": [1]}}`, LimitOptions{MaxDepth: 2}, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input, WithLimits(tt.limits))
+
+			var limitErr *LimitError
+			assert.True(t, errors.As(err, &limitErr))
+			assert.Equal(t, LimitDepth, limitErr.Kind)
+			assert.Equal(t, tt.offset, limitErr.Offset)
+		})
+	}
+
+	_, err := Parse(`{"a": {"b": [1]}, "c": [2]}`, WithLimits(LimitOptions{MaxDepth: 3}))
+
+	assert.Nil(t, err)
+}
+
+func TestDecoder_Decode_failure_over_limit(t *testing.T) {
+	input := "{\"a\": 1}\n{\"a\": \"a long one\"}\n{\"a\": 2}"
+	decoder := NewDecoder(strings.NewReader(input), WithLimits(LimitOptions{MaxSize: 10}))
+
+	_, err := decoder.Decode()
+	assert.Nil(t, err)
+
+	_, err = decoder.Decode()
+	var docErr *DocumentError
+	assert.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 2, docErr.Line)
+
+	_, err = decoder.Decode()
+	assert.Nil(t, err)
+}
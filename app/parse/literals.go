@@ -0,0 +1,107 @@
+package parse
+
+import "strings"
+
+// Literal is the JSON a spelling of null or a boolean stands for.
+type Literal string
+
+const (
+	LiteralNull  Literal = "null"
+	LiteralTrue  Literal = "true"
+	LiteralFalse Literal = "false"
+)
+
+// Dialect is where the input was copied from, which decides the spellings of null and booleans we recognise.
+type Dialect int
+
+const (
+	// DialectAny recognises the spellings that can't reasonably be meant as words: Python's `None`, `True` and
+	// `False`, `nil`, `undefined`, and `NULL`, `TRUE` and `FALSE`.  YAML's `yes`, `no`, `on` and `off` are left
+	// out, ask for DialectYAML if you want those.
+	DialectAny Dialect = iota
+	// DialectJSON only recognises `true`, `false` and `null`.
+	DialectJSON
+	// DialectPython recognises `None`, `True` and `False`.
+	DialectPython
+	// DialectJavaScript recognises `undefined`.
+	DialectJavaScript
+	// DialectGo recognises `nil`.
+	DialectGo
+	// DialectSQL recognises `NULL`, `TRUE` and `FALSE`, in upper or title case (`Null`).  Mixed case like `nUlL`
+	// isn't.
+	DialectSQL
+	// DialectYAML recognises YAML 1.1's `~`, `yes`, `no`, `on` and `off`, in lower, title or upper case (`yes`,
+	// `Yes`, `YES`), as well as the same spellings as DialectSQL.
+	DialectYAML
+)
+
+var pythonLiterals = map[string]Literal{"None": LiteralNull, "True": LiteralTrue, "False": LiteralFalse}
+
+var javaScriptLiterals = map[string]Literal{"undefined": LiteralNull}
+
+var goLiterals = map[string]Literal{"nil": LiteralNull}
+
+var sqlLiterals = map[string]Literal{
+	"NULL": LiteralNull, "Null": LiteralNull,
+	"TRUE": LiteralTrue, "True": LiteralTrue,
+	"FALSE": LiteralFalse, "False": LiteralFalse,
+}
+
+var yamlLiterals = map[string]Literal{
+	"~":   LiteralNull,
+	"yes": LiteralTrue, "Yes": LiteralTrue, "YES": LiteralTrue,
+	"on": LiteralTrue, "On": LiteralTrue, "ON": LiteralTrue,
+	"no": LiteralFalse, "No": LiteralFalse, "NO": LiteralFalse,
+	"off": LiteralFalse, "Off": LiteralFalse, "OFF": LiteralFalse,
+}
+
+// dialectLiterals is the spellings each dialect recognises, on top of JSON's own.
+var dialectLiterals = map[Dialect][]map[string]Literal{
+	DialectAny:        {pythonLiterals, javaScriptLiterals, goLiterals, sqlLiterals},
+	DialectPython:     {pythonLiterals},
+	DialectJavaScript: {javaScriptLiterals},
+	DialectGo:         {goLiterals},
+	DialectSQL:        {sqlLiterals},
+	DialectYAML:       {sqlLiterals, yamlLiterals},
+}
+
+// LiteralOptions controls which bare words we read as null or a boolean while repairing input, eg. `None` in
+// `{'a': None}` pasted out of Python.  Only unquoted values count, so `"None"` is always left as a string.
+type LiteralOptions struct {
+	Dialect Dialect
+	// Table adds spellings of our own, on top of the dialect's, eg. `{"N/A": LiteralNull}`.  These win over the
+	// dialect if they spell the same thing differently.
+	Table map[string]Literal
+	// EmptyAsString keeps a key with nothing after it (`key:` and then a comma or newline) as an empty string.
+	// By default it's null.
+	EmptyAsString bool
+}
+
+// table is every spelling we recognise, and what it stands for.
+func (o LiteralOptions) table() map[string]Literal {
+	table := map[string]Literal{"null": LiteralNull, "true": LiteralTrue, "false": LiteralFalse}
+	for _, literals := range dialectLiterals[o.Dialect] {
+		for spelling, literal := range literals {
+			table[spelling] = literal
+		}
+	}
+	for spelling, literal := range o.Table {
+		table[spelling] = literal
+	}
+	if o.EmptyAsString {
+		delete(table, "")
+	} else {
+		table[""] = LiteralNull
+	}
+	return table
+}
+
+// formatValue is `format` for values, which also turns unquoted spellings of null and booleans into JSON.
+func formatValue(input string, quoted bool, literals map[string]Literal) string {
+	if !quoted {
+		if literal, ok := literals[strings.TrimSpace(input)]; ok {
+			return string(literal)
+		}
+	}
+	return format(input)
+}
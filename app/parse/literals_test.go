@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_reads_alternate_null_and_boolean_spellings(t *testing.T) {
+	input := `{'python': None, 'yes': True, 'no': False, 'go': nil, 'js': undefined, 'sql': NULL, 'shout': TRUE}`
+	expected := `{
+    "go": null,
+    "js": null,
+    "no": false,
+    "python": null,
+    "shout": true,
+    "sql": null,
+    "yes": true
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_leaves_quoted_spellings_as_strings(t *testing.T) {
+	input := `{"a": "None", "b": "True", "c": "", "d": "nil"}`
+	expected := `{
+    "a": "None",
+    "b": "True",
+    "c": "",
+    "d": "nil"
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_empty_values_are_null(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"comma", `{"a":, "b": 1}`},
+		{"newline", "a:\nb: 1"},
+		{"last", `{"b": 1, "a":}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, `{"a": null, "b": 1}`, result)
+		})
+	}
+}
+
+func TestParser_Parse_success_literal_options(t *testing.T) {
+	input := "enabled: yes\nverbose: off\nmissing: ~\nname: None\nempty:\nstatus: N/A"
+
+	tests := []struct {
+		name     string
+		options  LiteralOptions
+		expected string
+	}{
+		{
+			"any",
+			LiteralOptions{},
+			`{"enabled": "yes", "verbose": "off", "missing": "~", "name": null, "empty": null, "status": "N/A"}`,
+		},
+		{
+			"yaml",
+			LiteralOptions{Dialect: DialectYAML},
+			`{"enabled": true, "verbose": false, "missing": null, "name": "None", "empty": null, "status": "N/A"}`,
+		},
+		{
+			"json",
+			LiteralOptions{Dialect: DialectJSON, EmptyAsString: true},
+			`{"enabled": "yes", "verbose": "off", "missing": "~", "name": "None", "empty": "", "status": "N/A"}`,
+		},
+		{
+			"table",
+			LiteralOptions{Dialect: DialectPython, Table: map[string]Literal{"N/A": LiteralNull, "yes": LiteralTrue}},
+			`{"enabled": true, "verbose": "off", "missing": "~", "name": null, "empty": null, "status": null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{Literals: tt.options}
+			result, err := p.Parse(input)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParser_Parse_success_literals_only_in_lower_title_or_upper_case(t *testing.T) {
+	input := "a: Null\nb: TRUE\nc: nUlL\nd: YES\ne: yEs"
+
+	result, err := (&Parser{Literals: LiteralOptions{Dialect: DialectYAML}}).Parse(input)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"a": null, "b": true, "c": "nUlL", "d": true, "e": "yEs"}`, result)
+}
@@ -0,0 +1,183 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var markdownFence = regexp.MustCompile("^([ \t]*)(```+|~~~+)[ \t]*([\\w.+-]*)[ \t]*$")
+var markdownBlockquote = regexp.MustCompile(`^[ \t]*>[ \t]?`)
+var markdownListMarker = regexp.MustCompile(`^[ \t]*([-*+]|\d+[.)])[ \t]+`)
+
+// fenceLanguages are the code block languages we'll pull JSON out of.  No language at all is fine too.
+var fenceLanguages = map[string]bool{
+	"":           true,
+	"json":       true,
+	"jsonc":      true,
+	"json5":      true,
+	"jsonl":      true,
+	"ndjson":     true,
+	"js":         true,
+	"javascript": true,
+	"text":       true,
+}
+
+// stripMarkdown takes off the Markdown that comes along with JSON copied out of READMEs, issues and chat tools.
+// It returns the text with any blockquote markers removed, which the offsets of the documents refer to, and
+// one document per fenced code block.  With no fences we hand back a single document, stripped of list markers
+// and any prose around it.
+func (s *parseState) stripMarkdown(input string) (string, []document, error) {
+	text := stripLinePrefixes(input, markdownBlockquote)
+	if documents := findFencedBlocks(text); len(documents) > 0 {
+		return text, documents, nil
+	}
+	// Each of these goes over the whole input, so we check we're still wanted in between.
+	if err := s.cancelled(); err != nil {
+		return "", nil, err
+	}
+
+	text = stripListMarkers(text)
+	if err := s.cancelled(); err != nil {
+		return "", nil, err
+	}
+	// In JSON Lines, every line is a document, so a line of prose is a bad one to report rather than drop.
+	if len(splitDocumentLines(text)) < 2 {
+		if err := s.cancelled(); err != nil {
+			return "", nil, err
+		}
+		text = stripProse(text)
+	}
+	text = strings.TrimSpace(text)
+	return text, []document{{text: text}}, nil
+}
+
+// stripLinePrefixes only strips the prefix if every line that has something on it starts with it, repeating
+// for nested prefixes like `> >`.
+func stripLinePrefixes(input string, prefix *regexp.Regexp) string {
+	lines := strings.Split(input, "\n")
+	for {
+		seen := false
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if !prefix.MatchString(line) {
+				return strings.Join(lines, "\n")
+			}
+			seen = true
+		}
+		if !seen {
+			return strings.Join(lines, "\n")
+		}
+		for i, line := range lines {
+			lines[i] = prefix.ReplaceAllString(line, "")
+		}
+	}
+}
+
+// stripListMarkers handles JSON pasted as list items, as long as the input starts with a list item and every
+// other line is either another item, or an indented continuation of one.
+func stripListMarkers(input string) string {
+	lines := strings.Split(input, "\n")
+	if !markdownListMarker.MatchString(lines[0]) {
+		return input
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" || markdownListMarker.MatchString(line) {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			return input
+		}
+	}
+	for i, line := range lines {
+		if markdownListMarker.MatchString(line) {
+			lines[i] = markdownListMarker.ReplaceAllString(line, "")
+			continue
+		}
+		lines[i] = strings.TrimLeft(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func findFencedBlocks(input string) []document {
+	var documents []document
+	lines := strings.SplitAfter(input, "\n")
+	offset := 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		offset += len(line)
+
+		// Most lines aren't fences, and checking the start is much cheaper than the regex.
+		trimmed := strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+			continue
+		}
+		match := markdownFence.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			continue
+		}
+		indent, fence, language := match[1], match[2], strings.ToLower(match[3])
+
+		// An unclosed fence runs to the end of the input, chat tools love to cut those off.
+		contentOffset := offset
+		content := strings.Builder{}
+		for i++; i < len(lines); i++ {
+			offset += len(lines[i])
+			if strings.TrimSpace(lines[i]) == fence {
+				break
+			}
+			content.WriteString(strings.TrimPrefix(lines[i], indent))
+		}
+
+		text := strings.TrimSpace(content.String())
+		if !fenceLanguages[language] || text == "" {
+			continue
+		}
+		documents = append(documents, document{text: text, offset: contentOffset})
+	}
+	return documents
+}
+
+// stripProse drops lines of prose that come before or after the JSON, eg. "Here's the response:".  We only do it
+// when the JSON starts and ends on lines of its own, so the `key: value` style input is left alone.
+func stripProse(input string) string {
+	lines := strings.Split(input, "\n")
+	first, last := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if first < 0 && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) {
+			first = i
+		}
+		if strings.HasSuffix(trimmed, "}") || strings.HasSuffix(trimmed, "]") {
+			last = i
+		}
+	}
+	if first < 0 || last < first {
+		return input
+	}
+	for _, line := range lines[:first] {
+		if !isProse(line) {
+			return input
+		}
+	}
+	for _, line := range lines[last+1:] {
+		if !isProse(line) {
+			return input
+		}
+	}
+	return strings.Join(lines[first:last+1], "\n")
+}
+
+// isProse is a loose guess at whether a line is a sentence rather than data.  Sentences have a few words in them,
+// and don't start with something that looks like a key.
+func isProse(line string) bool {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return true
+	}
+	if len(words) < 3 || strings.HasSuffix(words[0], ":") || strings.Contains(line, `":`) {
+		return false
+	}
+	return true
+}
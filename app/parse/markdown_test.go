@@ -0,0 +1,132 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_strips_code_fence_and_prose(t *testing.T) {
+	input := "Here is the response we got back:\n\n```json\n{\"status\": \"ok\"}\n```\n\nLet me know if you need more."
+	expected := `{
+    "status": "ok"
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_strips_blockquote_markers(t *testing.T) {
+	input := `> {
+>   "quoted": true
+> }`
+	expected := `{
+    "quoted": true
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_ParseResult_success_offsets_are_into_the_stripped_text(t *testing.T) {
+	input := "> {\n>   'a': 1,\n>   b: 2\n> }"
+
+	result, err := NewParser().ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  'a': 1,\n  b: 2\n}", result.Text)
+	assert.NotEmpty(t, result.Repairs)
+	for _, repair := range result.Repairs {
+		assert.Equal(t, repair.Original, result.Text[repair.Start:repair.End])
+	}
+}
+
+func TestParser_ParseResult_success_text_is_the_input_without_markers(t *testing.T) {
+	input := "Here you go: {a: 1}"
+
+	result, err := NewParser().ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, input, result.Text)
+}
+
+func TestParse_success_strips_fence_inside_blockquoted_list_item(t *testing.T) {
+	input := "> - The payload:\n>   ```\n>   {\"a\": [1, 2]}\n>   ```"
+	expected := `{
+    "a": [
+        1,
+        2
+    ]
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_strips_list_markers(t *testing.T) {
+	input := `- {"a": 1}
+- {"a": 2}`
+	expected := `{
+    "a": 1
+}
+
+{
+    "a": 2
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_strips_prose_without_fences(t *testing.T) {
+	input := `Sure, here it is
+{
+  "a": 1
+}
+Hope that helps you out`
+	expected := `{
+    "a": 1
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_several_fenced_blocks(t *testing.T) {
+	input := "Request:\n```json\n{\"id\": 1}\n```\nResponse:\n```bash\ncurl localhost\n```\n~~~\n{\"ok\": true}\n~~~"
+	expected := `[
+    {
+        "id": 1
+    },
+    {
+        "ok": true
+    }
+]`
+
+	p := &Parser{DocumentFormat: DocumentsArray}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_failure_reports_line_of_bad_fenced_block(t *testing.T) {
+	input := "```\n{\"id\": 1}\n```\n\n```\n{\"id\"}\n```"
+
+	result, err := Parse(input)
+
+	assert.Equal(t, "{\n    \"id\": 1\n}", result)
+	docErrs, ok := err.(DocumentErrors)
+	assert.True(t, ok)
+	assert.Len(t, docErrs, 1)
+	assert.Equal(t, 6, docErrs[0].Line)
+}
@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// unmarshal is `json.Unmarshal`, except numbers come back as a `json.Number`.  That keeps their literal text as
+// is, so big IDs (`1234567890123456789`) and trailing zeros (`1.10`) make it to the output untouched.
+func unmarshal(data string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(v)
+	if err == io.EOF {
+		// Nothing to decode at all, which `json.Unmarshal` considers unexpected.
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	// `json.Unmarshal` refuses anything after the value, the decoder is happy to leave it for the next call.
+	_, err = decoder.Token()
+	if err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// normalizeNumbers walks the data rewriting every number into the shortest form that means the same thing.
+func normalizeNumbers(input interface{}) interface{} {
+	switch value := input.(type) {
+	case map[string]interface{}:
+		for key, val := range value {
+			value[key] = normalizeNumbers(val)
+		}
+		return value
+	case []interface{}:
+		for i, val := range value {
+			value[i] = normalizeNumbers(val)
+		}
+		return value
+	case json.Number:
+		return normalizeNumber(value)
+	default:
+		return value
+	}
+}
+
+func normalizeNumber(number json.Number) json.Number {
+	literal := number.String()
+	if !strings.ContainsAny(literal, ".eE") {
+		// Integers are already as short as they get, and going through a float64 would cost us precision.
+		if literal == "-0" {
+			return "0"
+		}
+		return number
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		// Out of range for a float64, we can't do any better than what we were given.
+		return number
+	}
+	result, err := json.Marshal(f)
+	if err != nil {
+		return number
+	}
+	return json.Number(result)
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_preserves_big_numbers_and_decimals(t *testing.T) {
+	input := `{"id": 1234567890123456789, "price": 1.10, "exp": 1E2, "list": [0.000000000000000000001, -0]}`
+	expected := `{
+    "exp": 1E2,
+    "id": 1234567890123456789,
+    "list": [
+        0.000000000000000000001,
+        -0
+    ],
+    "price": 1.10
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_quotes_unquoted_numbers_json_doesnt_take(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"leading_zeros", "a: 007\nb: 1", `{"a":"007","b":1}`},
+		{"zip_code", "{zip: 02134, n: 1.10}", `{"n":1.10,"zip":"02134"}`},
+		{"not_quite_numbers", "{a: 1., b: .5, c: -01, d: -0}", `{"a":"1.","b":".5","c":"-01","d":-0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(tt.input, WithCompact())
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParse_success_preserves_numbers_in_embedded_json_strings(t *testing.T) {
+	input := `{"payload": "{\"id\": 1234567890123456789}"}`
+	expected := `{
+    "payload": {
+        "id": 1234567890123456789
+    }
+}`
+
+	result, err := Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_Parse_success_normalizes_numbers(t *testing.T) {
+	input := `{"id": 1234567890123456789, "price": 1.10, "exp": 1E2, "zero": -0}`
+	expected := `{
+    "exp": 100,
+    "id": 1234567890123456789,
+    "price": 1.1,
+    "zero": 0
+}`
+
+	p := &Parser{NormalizeNumbers: true}
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
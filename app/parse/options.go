@@ -0,0 +1,158 @@
+package parse
+
+// Option changes one of the Parser's settings, for `Parse` and `NewParser`.  Options are applied in order, so a
+// later one wins over an earlier one that sets the same thing.
+type Option func(p *Parser)
+
+// NewParser makes a Parser with the options applied, which can be used over and over again.
+func NewParser(options ...Option) *Parser {
+	p := &Parser{}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// WithDialect picks the spellings of null and booleans we recognise, eg. DialectPython for `None`.
+func WithDialect(dialect Dialect) Option {
+	return func(p *Parser) {
+		p.Literals.Dialect = dialect
+	}
+}
+
+// WithLiterals replaces all the literal settings, including the dialect.
+func WithLiterals(literals LiteralOptions) Option {
+	return func(p *Parser) {
+		p.Literals = literals
+	}
+}
+
+// WithIndent indents each level of the output with the given text, eg. "\t" or two spaces.
+func WithIndent(indent string) Option {
+	return func(p *Parser) {
+		p.Indent = indent
+		p.Compact = false
+	}
+}
+
+// WithCompact writes the output on a single line.
+func WithCompact() Option {
+	return func(p *Parser) {
+		p.Compact = true
+	}
+}
+
+// WithKeyOrder picks the order object keys are written out in, eg. KeysInputOrder to keep them as they were.
+func WithKeyOrder(order KeyOrder) Option {
+	return func(p *Parser) {
+		p.KeyOrder = order
+	}
+}
+
+// WithDotNotation replaces all the dot notation settings.
+func WithDotNotation(dotNotation DotNotationOptions) Option {
+	return func(p *Parser) {
+		p.DotNotation = dotNotation
+	}
+}
+
+// WithoutDotNotation leaves dot notation keys as they are, instead of expanding them.
+func WithoutDotNotation() Option {
+	return func(p *Parser) {
+		p.DotNotation.Disabled = true
+	}
+}
+
+// WithoutDynamoDB leaves DynamoDB attribute values (`{"N": "5"}`) as they are, instead of unwrapping them.
+func WithoutDynamoDB() Option {
+	return func(p *Parser) {
+		p.DynamoDB.Disabled = true
+	}
+}
+
+// WithStringDecoding replaces all the settings for unpacking JSON stored in strings.
+func WithStringDecoding(stringDecoding StringDecodingOptions) Option {
+	return func(p *Parser) {
+		p.StringDecoding = stringDecoding
+	}
+}
+
+// WithoutStringDecoding leaves strings as they are, even if there's JSON inside.
+func WithoutStringDecoding() Option {
+	return func(p *Parser) {
+		p.StringDecoding.Disabled = true
+	}
+}
+
+// WithJWT replaces all the settings for decoding JSON Web Tokens.
+func WithJWT(jwt JWTOptions) Option {
+	return func(p *Parser) {
+		p.JWT = jwt
+	}
+}
+
+// WithNormalizedNumbers rewrites numbers into their shortest form, instead of keeping them as they were written.
+func WithNormalizedNumbers() Option {
+	return func(p *Parser) {
+		p.NormalizeNumbers = true
+	}
+}
+
+// WithCoercion converts values between strings and numbers, booleans and nulls.
+func WithCoercion(coercion CoercionOptions) Option {
+	return func(p *Parser) {
+		p.Coercion = coercion
+	}
+}
+
+// WithDuplicateKeys picks what we do about a key that shows up more than once in an object.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) Option {
+	return func(p *Parser) {
+		p.DuplicateKeys = policy
+	}
+}
+
+// WithDocumentFormat picks how input holding several top level documents is written back out.
+func WithDocumentFormat(format DocumentFormat) Option {
+	return func(p *Parser) {
+		p.DocumentFormat = format
+	}
+}
+
+// WithEmbedded picks what we output for JSON sitting in the middle of other text.
+func WithEmbedded(mode EmbeddedMode) Option {
+	return func(p *Parser) {
+		p.Embedded = mode
+	}
+}
+
+// WithTruncation picks what we do with input that was cut off partway through.
+func WithTruncation(mode TruncationMode) Option {
+	return func(p *Parser) {
+		p.Truncation = mode
+	}
+}
+
+// WithStrictErrors fails the parse for anything we'd otherwise have to pick a winner for, duplicate keys and dot
+// notation keys that conflict, rather than reporting it in the diagnostics.
+func WithStrictErrors() Option {
+	return func(p *Parser) {
+		p.DuplicateKeys = DuplicateError
+		p.DotNotation.Conflict = DotConflictError
+	}
+}
+
+// WithStrict only accepts input that's already valid JSON, and makes the optional checks asked for.
+func WithStrict(strict StrictOptions) Option {
+	return func(p *Parser) {
+		p.Strict = strict
+		p.Strict.Enabled = true
+	}
+}
+
+// WithLimits bounds how big and how deeply nested the input can be.
+func WithLimits(limits LimitOptions) Option {
+	return func(p *Parser) {
+		p.Limits = limits
+	}
+}
@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_with_options(t *testing.T) {
+	input := `{"zebra": 1, "apple": {"b": None, "a": 1.50}, "mango": [1, 2]}`
+
+	tests := []struct {
+		name     string
+		options  []Option
+		expected string
+	}{
+		{
+			"defaults",
+			nil,
+			"{\n    \"apple\": {\n        \"a\": 1.50,\n        \"b\": null\n    },\n    \"mango\": [\n        1,\n" +
+				"        2\n    ],\n    \"zebra\": 1\n}",
+		},
+		{
+			"input_order_and_tabs",
+			[]Option{WithKeyOrder(KeysInputOrder), WithIndent("\t")},
+			"{\n\t\"zebra\": 1,\n\t\"apple\": {\n\t\t\"b\": null,\n\t\t\"a\": 1.50\n\t},\n\t\"mango\": [\n\t\t1,\n" +
+				"\t\t2\n\t]\n}",
+		},
+		{
+			"compact_and_numbers",
+			[]Option{WithCompact(), WithNormalizedNumbers()},
+			`{"apple":{"a":1.5,"b":null},"mango":[1,2],"zebra":1}`,
+		},
+		{
+			"dialect_and_coercion",
+			[]Option{WithCompact(), WithDialect(DialectJSON), WithCoercion(CoercionOptions{Mode: CoerceStrings})},
+			`{"apple":{"a":"1.50","b":"None"},"mango":["1","2"],"zebra":"1"}`,
+		},
+		{
+			"later_options_win",
+			[]Option{WithCompact(), WithIndent("  ")},
+			"{\n  \"apple\": {\n    \"a\": 1.50,\n    \"b\": null\n  },\n  \"mango\": [\n    1,\n    2\n  ],\n" +
+				"  \"zebra\": 1\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(input, tt.options...)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNewParser_success_input_order_with_dot_notation(t *testing.T) {
+	input := `{"z": 1, "a.y": 2, "a.b": 3, "c": 4}`
+
+	p := NewParser(WithKeyOrder(KeysInputOrder), WithCompact())
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"z":1,"a":{"y":2,"b":3},"c":4}`, result)
+
+	p = NewParser(WithKeyOrder(KeysInputOrder), WithCompact(), WithoutDotNotation())
+	result, err = p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"z":1,"a.y":2,"a.b":3,"c":4}`, result)
+}
+
+func TestNewParser_success_documents_keep_options(t *testing.T) {
+	input := "{\"b\": 1, \"a\": 2}\n{\"d\": 3, \"c\": 4}"
+
+	p := NewParser(WithKeyOrder(KeysInputOrder), WithDocumentFormat(DocumentsNDJSON))
+	result, err := p.Parse(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"b\":1,\"a\":2}\n{\"d\":3,\"c\":4}", result)
+}
+
+func TestParse_failure_with_strict_errors(t *testing.T) {
+	_, err := Parse(`{"a": 1, "a": 2}`, WithStrictErrors())
+
+	var duplicate *DuplicateKeyError
+	assert.True(t, errors.As(err, &duplicate))
+
+	_, err = Parse(`{"a": 1, "a.b": 2}`, WithStrictErrors())
+
+	var conflict *DotKeyConflictError
+	assert.True(t, errors.As(err, &conflict))
+}
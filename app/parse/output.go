@@ -0,0 +1,183 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultIndent = "    "
+
+// KeyOrder is the order we write object keys out in.
+type KeyOrder int
+
+const (
+	// KeysSorted writes keys in alphabetical order, the same as `json.Marshal` does.
+	KeysSorted KeyOrder = iota
+	// KeysInputOrder writes keys in the order they showed up in the input.  Keys we added ourselves, like
+	// objects made out of dot notation keys, go in the order we made them.
+	KeysInputOrder
+)
+
+func (p *Parser) indent() string {
+	if p.Indent == "" {
+		return defaultIndent
+	}
+	return p.Indent
+}
+
+// marshal writes the data out the way the Parser is set up to, indented unless it's Compact.
+func (s *parseState) marshal(data interface{}) (string, error) {
+	if s.Compact {
+		return s.marshalCompact(data)
+	}
+	var buffer strings.Builder
+	err := s.encode(&buffer, data, s.indent(), 0)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// marshalCompact writes the data out on a single line, like for NDJSON.
+func (s *parseState) marshalCompact(data interface{}) (string, error) {
+	var buffer strings.Builder
+	err := s.encode(&buffer, data, "", 0)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// encode is `json.MarshalIndent`, except objects have their keys in the Parser's KeyOrder.  An empty indent writes
+// it out compacted, the same as `json.Marshal`.  We write the indentation as we go, rather than running the output
+// through `json.Indent` afterwards, as that's a whole other copy of it.
+func (s *parseState) encode(buffer *strings.Builder, data interface{}, indent string, depth int) error {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			buffer.WriteString("{}")
+			return nil
+		}
+		var keys []string
+		if s.KeyOrder == KeysInputOrder {
+			keys = s.orderedKeys(value)
+		} else {
+			keys = make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeNewline(buffer, indent, depth+1)
+			err := writeString(buffer, key)
+			if err != nil {
+				return err
+			}
+			buffer.WriteByte(':')
+			if indent != "" {
+				buffer.WriteByte(' ')
+			}
+			err = s.encode(buffer, value[key], indent, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		writeNewline(buffer, indent, depth)
+		buffer.WriteByte('}')
+		return nil
+	case []interface{}:
+		if len(value) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+		buffer.WriteByte('[')
+		for i, val := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeNewline(buffer, indent, depth+1)
+			err := s.encode(buffer, val, indent, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		writeNewline(buffer, indent, depth)
+		buffer.WriteByte(']')
+		return nil
+	case string:
+		return writeString(buffer, value)
+	case json.Number:
+		if !isJSONNumber(string(value)) {
+			return fmt.Errorf("json: invalid number literal %q", string(value))
+		}
+		buffer.WriteString(string(value))
+		return nil
+	case bool:
+		buffer.WriteString(strconv.FormatBool(value))
+		return nil
+	case nil:
+		buffer.WriteString("null")
+		return nil
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if indent == "" {
+			buffer.Write(encoded)
+			return nil
+		}
+		var indented bytes.Buffer
+		err = json.Indent(&indented, encoded, strings.Repeat(indent, depth), indent)
+		buffer.Write(indented.Bytes())
+		return err
+	}
+}
+
+// writeNewline starts a new line at the depth, if we're indenting.
+func writeNewline(buffer *strings.Builder, indent string, depth int) {
+	if indent == "" {
+		return
+	}
+	buffer.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		buffer.WriteString(indent)
+	}
+}
+
+// writeString writes a string out quoted, the same as `json.Marshal`.  Most strings have nothing in them to escape,
+// so we only hand the ones that do over to it.
+func writeString(buffer *strings.Builder, value string) error {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c >= utf8.RuneSelf || strings.IndexByte(`"\\<>&`, c) >= 0 {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buffer.Write(encoded)
+			return nil
+		}
+	}
+	buffer.WriteByte('"')
+	buffer.WriteString(value)
+	buffer.WriteByte('"')
+	return nil
+}
+
+// isJSONNumber is whether the text is a number, exactly as JSON allows it.
+func isJSONNumber(text string) bool {
+	d := valueDecoder{data: text}
+	_, err := d.number()
+	return err == nil && d.pos == len(text)
+}
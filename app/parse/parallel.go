@@ -0,0 +1,139 @@
+package parse
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// parallelJob is a document handed to a worker, and where the worker sends back what it made of it.
+type parallelJob struct {
+	doc    streamedDocument
+	result chan parallelResult
+}
+
+// parallelResult is a document formatted the way it'll be written out, or why it couldn't be.
+type parallelResult struct {
+	output string
+	err    error
+}
+
+// StreamParallel is Stream, but with the documents parsed on a pool of `workers` goroutines at once, for NDJSON
+// exports big enough that parsing a line at a time is the slow part.  The output is in the same order as the input,
+// and is the same as Stream would've written.  Zero or fewer workers means one for each CPU.  We only read ahead
+// a couple of documents per worker, so memory stays bounded however big the stream is.
+//
+// As with Stream, documents that can't be parsed are skipped, and come back together as DocumentErrors, each with
+// the line it started on, once we've been through the rest.
+func (p *Parser) StreamParallel(reader io.Reader, writer io.Writer, workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	decoder := p.NewDecoder(reader)
+	encoder := p.NewEncoder(writer)
+
+	// pending holds each document's result, in the order they were read, so we can write them out in that order
+	// whichever worker finishes first.  Its buffer is how far the reader can get ahead of the writer.
+	pending := make(chan chan parallelResult, workers*2)
+	jobs := make(chan parallelJob)
+	// done tells the reader to stop, if we hit an error we can't carry on from.
+	done := make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.readParallel(decoder, pending, jobs, done)
+	}()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.result <- p.parseParallel(encoder, job.doc)
+			}
+		}()
+	}
+
+	err := p.writeParallel(encoder, pending)
+	close(done)
+	wg.Wait()
+	return err
+}
+
+// readParallel reads documents and hands them out to the workers, until it runs out of them or is told to stop.  A
+// read error is passed along in order, behind the documents before it.
+func (p *Parser) readParallel(decoder *Decoder, pending chan<- chan parallelResult, jobs chan<- parallelJob,
+	done <-chan struct{}) {
+	defer close(pending)
+	defer close(jobs)
+	for {
+		doc, err := decoder.next()
+		if err == io.EOF {
+			return
+		}
+		// The buffer means the worker never waits on the writer to hand its result over.
+		result := make(chan parallelResult, 1)
+		if err != nil {
+			result <- parallelResult{err: err}
+		}
+		select {
+		case pending <- result:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case jobs <- parallelJob{doc: doc, result: result}:
+		case <-done:
+			return
+		}
+	}
+}
+
+// parseParallel parses and formats a document on a worker.  Formatting doesn't depend on what's been written before
+// it, so only writing it out is left for the writer to do in order.
+func (p *Parser) parseParallel(encoder *Encoder, doc streamedDocument) (result parallelResult) {
+	defer recoverPanic(&result.err)
+	data, s, err := p.parseStreamed(doc)
+	if err != nil && !isTruncated(err) {
+		return parallelResult{err: err}
+	}
+	output, formatErr := encoder.format(s, data)
+	if formatErr != nil {
+		return parallelResult{err: formatErr}
+	}
+	return parallelResult{output: output, err: err}
+}
+
+// writeParallel writes out the results in the order the documents were read, and closes the encoder once they're
+// all written.
+func (p *Parser) writeParallel(encoder *Encoder, pending <-chan chan parallelResult) error {
+	var errs DocumentErrors
+	for result := range pending {
+		r := <-result
+		if docErr, ok := r.err.(*DocumentError); ok {
+			errs = append(errs, docErr)
+			if !isTruncated(r.err) {
+				continue
+			}
+		} else if r.err != nil {
+			return r.err
+		}
+		err := encoder.write(r.output)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := encoder.Close()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParser_StreamParallel_success_matches_stream(t *testing.T) {
+	for _, format := range []DocumentFormat{DocumentsPretty, DocumentsArray, DocumentsNDJSON} {
+		for _, workers := range []int{0, 1, 2, 8} {
+			t.Run(fmt.Sprintf("format_%d_%d_workers", format, workers), func(t *testing.T) {
+				p := NewParser(WithDocumentFormat(format), WithKeyOrder(KeysInputOrder))
+				expected := bytes.Buffer{}
+				err := p.Stream(strings.NewReader(streamInput), &expected)
+				assert.Nil(t, err)
+
+				output := bytes.Buffer{}
+				err = p.StreamParallel(strings.NewReader(streamInput), &output, workers)
+
+				assert.Nil(t, err)
+				assert.Equal(t, expected.String(), output.String())
+			})
+		}
+	}
+}
+
+func TestParser_StreamParallel_success_keeps_order(t *testing.T) {
+	// Plenty of lines, with some much slower to parse than others, so the workers finish out of order.
+	lines := make([]string, 500)
+	expected := make([]string, 500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("{id: %d}", i)
+		if i%7 == 0 {
+			lines[i] = fmt.Sprintf("{id: %d, padding: '%s'}", i, strings.Repeat("x", 10000))
+		}
+		expected[i] = fmt.Sprintf(`"id":%d`, i)
+	}
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).StreamParallel(strings.NewReader(strings.Join(lines, "\n")),
+		&output, 8)
+
+	assert.Nil(t, err)
+	outputLines := strings.Split(output.String(), "\n")
+	assert.Len(t, outputLines, 500)
+	for i, line := range outputLines {
+		assert.Contains(t, line, expected[i])
+	}
+}
+
+func TestParser_StreamParallel_failure_reports_bad_lines(t *testing.T) {
+	input := "{\"a\": 1}\nnope\n{\"a\": 2}\n:\n{\"a\": 3}"
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).StreamParallel(strings.NewReader(input), &output, 4)
+
+	var errs DocumentErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 2, errs[0].Line)
+	assert.Equal(t, 4, errs[1].Line)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n{\"a\":3}", output.String())
+}
+
+func TestParser_StreamParallel_failure_bad_middle_lines_keep_the_rest(t *testing.T) {
+	lines := make([]string, 200)
+	var expected []string
+	for i := range lines {
+		switch {
+		case i == 50:
+			lines[i] = `{"id": 50, "b": "trunc`
+			expected = append(expected, `{"b":"trunc","id":50}`)
+		case i == 100:
+			lines[i] = "not json at all"
+		default:
+			lines[i] = fmt.Sprintf(`{"id": %d}`, i)
+			expected = append(expected, fmt.Sprintf(`{"id":%d}`, i))
+		}
+	}
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).StreamParallel(strings.NewReader(strings.Join(lines, "\n")),
+		&output, 4)
+
+	var errs DocumentErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 51, errs[0].Line)
+	assert.Equal(t, 50, errs[0].Index)
+	assert.True(t, isTruncated(errs[0]))
+	assert.Equal(t, 101, errs[1].Line)
+	assert.Equal(t, 100, errs[1].Index)
+	assert.False(t, isTruncated(errs[1]))
+	assert.Equal(t, strings.Join(expected, "\n"), output.String())
+}
+
+// failingWriter fails every write after the first `ok` of them.
+type failingWriter struct {
+	ok int
+}
+
+func (w *failingWriter) Write(data []byte) (int, error) {
+	if w.ok == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.ok--
+	return len(data), nil
+}
+
+func TestParser_StreamParallel_failure_stops_on_write_error(t *testing.T) {
+	input := strings.Repeat("{\"a\": 1}\n", 1000)
+
+	p := NewParser(WithDocumentFormat(DocumentsNDJSON))
+
+	err := p.StreamParallel(strings.NewReader(input), &failingWriter{ok: 3}, 4)
+
+	assert.EqualError(t, err, "disk full")
+}
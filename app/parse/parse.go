@@ -1,49 +1,303 @@
 package parse
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
-// Also strip just lingering `\` characters at the start and end of the line.
-// We may have skipped the `"` characters during processing.
-var targetStartEndOfLineQuotes = regexp.MustCompile(`^\\*"|\\*"$|^\\*'|\\*'$`)
-var targetStartEndResidualSlashes = regexp.MustCompile(`^\\+|\\+$`)
+// Parser holds the settings used to turn input into formatted JSON.  The zero value is ready to use, and
+// behaves exactly like `Parse`.
+type Parser struct {
+	// DocumentFormat controls how input holding several top level documents is written back out.
+	DocumentFormat DocumentFormat
+	// Embedded controls what we output when the input is JSON sitting in the middle of other text.
+	Embedded EmbeddedMode
+	// Truncation controls what we do with input that was cut off partway through.
+	Truncation TruncationMode
+	// NormalizeNumbers rewrites numbers into their shortest form (`1.10` to `1.1`, `1E2` to `100`).  By default we
+	// keep numbers exactly as they were written.  Integers are kept exact either way.
+	NormalizeNumbers bool
+	// DuplicateKeys is what we do when the same key shows up more than once in an object, including when it only
+	// does once dot notation keys are expanded.
+	DuplicateKeys DuplicateKeyPolicy
+	// DotNotation controls how we expand dot notation keys (`a.b: 1`) into nested objects.
+	DotNotation DotNotationOptions
+	// DynamoDB controls how we unwrap DynamoDB attribute values into plain JSON.
+	DynamoDB DynamoDBOptions
+	// StringDecoding controls how we unpack JSON that's been stored in a string.
+	StringDecoding StringDecodingOptions
+	// JWT controls how we decode JSON Web Tokens, and what we verify them with.
+	JWT JWTOptions
+	// Literals controls which bare words, like Python's `None`, we read as null or a boolean.
+	Literals LiteralOptions
+	// Coercion controls converting strings like `"42"` into numbers, booleans and nulls, or the other way around.
+	Coercion CoercionOptions
+	// Indent is what each level of the output is indented with.  It defaults to four spaces.
+	Indent string
+	// Compact writes the output on a single line, instead of indenting it.
+	Compact bool
+	// KeyOrder is the order object keys are written out in.
+	KeyOrder KeyOrder
+	// Strict only accepts input that's already valid JSON, instead of repairing it.
+	Strict StrictOptions
+	// Limits bounds how big and how deeply nested the input can be.
+	Limits LimitOptions
+}
 
-func Parse(input string) (string, error) {
-	result := strings.TrimSpace(input)
+// parseState is everything that only lives as long as a single call to the Parser.
+type parseState struct {
+	*Parser
+	// ctx is what we check to see if we should stop early.  It's nil if there's no way to stop us.
+	ctx   context.Context
+	input string
+	// stripped is the input with the Markdown taken off it, if we couldn't find it in the input as it was.
+	stripped    string
+	diagnostics []Diagnostic
+	// keyOrders is the order keys went into each object, keyed by the object's address.
+	keyOrders map[uintptr]*keyOrder
+	// orders is keyOrders we've allocated ahead of time, but haven't used yet.
+	orders []keyOrder
+	// validText is the last text we checked was valid JSON, and validResult whether it was.
+	validText    string
+	validResult  bool
+	validChecked bool
+	// stringDepth is how many layers of strings we've unwrapped to get to what we're processing now.
+	stringDepth int
+	// coercionPaths is Coercion's Include and Exclude, once we've compiled them.
+	coercionPaths *pathPatterns
+	// literals is the table of spellings from Literals, once we've built it.
+	literals map[string]Literal
+	// repairs is every change we've made to the input.  While repairing, repairInput is the text being repaired,
+	// and anchors the keys and values we've written out of it.
+	repairs     []Repair
+	repairInput []rune
+	// repairASCII is the text being repaired, if it's all ASCII, so we can slice it rather than convert the runes.
+	repairASCII string
+	anchors     []repairAnchor
+	// ambiguities is everywhere we had to guess, and overrides the choices to make instead of our first guess,
+	// keyed by the ambiguity's id.  repairOffset is where the text being repaired starts in the input.
+	ambiguities  []Ambiguity
+	overrides    map[ambiguityID]int
+	repairOffset int
+}
 
-	// NOTE: We could try to just marshal/unmarshall blind first.  If that doesn't work, we could correct.
-	//  - We'd need - `stripUnneededQuotes` in `utils.go`
+// Parse takes partial, malformed, or otherwise mangled JSON and does its best to return it as formatted JSON.
+// With no options, it uses the defaults described on each of the Parser's fields.
+func Parse(input string, options ...Option) (string, error) {
+	return NewParser(options...).Parse(input)
+}
 
-	if IsComplexObject(result) {
+func (p *Parser) Parse(input string) (string, error) {
+	result, err := p.ParseResult(input)
+	return result.Output, err
+}
+
+// ParseResult is the same as Parse, but also hands back the diagnostics we collected along the way.
+func (p *Parser) ParseResult(input string) (Result, error) {
+	return p.ParseResultContext(context.Background(), input)
+}
+
+// ParseContext is the same as Parse, but gives up once the context is cancelled or past its deadline, so a
+// pathological input can't hold the caller up for ever.
+func ParseContext(ctx context.Context, input string, options ...Option) (string, error) {
+	return NewParser(options...).ParseContext(ctx, input)
+}
+
+func (p *Parser) ParseContext(ctx context.Context, input string) (string, error) {
+	result, err := p.ParseResultContext(ctx, input)
+	return result.Output, err
+}
+
+// ParseResultContext is the same as ParseResult, but gives up once the context is cancelled or past its deadline.
+// The error wraps the context's, so `errors.Is(err, context.DeadlineExceeded)` can tell it apart.
+func (p *Parser) ParseResultContext(ctx context.Context, input string) (result Result, err error) {
+	defer recoverPanic(&err)
+	s := &parseState{Parser: p, ctx: ctx, input: input}
+	output, err := s.parse()
+	text := input
+	if s.stripped != "" {
+		text = s.stripped
+	}
+	return Result{Output: output, Diagnostics: s.diagnostics, Repairs: s.repairs, Ambiguities: s.ambiguities,
+		Text: text}, err
+}
+
+func (s *parseState) parse() (string, error) {
+	if err := s.checkLimits(s.input); err != nil {
+		return "", err
+	}
+	if err := s.cancelled(); err != nil {
+		return "", err
+	}
+	if s.Strict.Enabled {
+		return s.parseStrict()
+	}
+	// Valid JSON is taken as it is.  There's no markdown or other documents around it, whatever it has inside.
+	text := strings.TrimSpace(s.input)
+	documents := []document{{text: text}}
+	if !s.valid(text) {
+		var err error
+		text, documents, err = s.stripMarkdown(text)
+		if err != nil {
+			return "", err
+		}
+		// Taking off quote or list markers leaves text we can't find in the input, so our offsets are into it.
+		if !strings.Contains(s.input, text) {
+			s.stripped = text
+		}
+	}
+	if len(documents) > 1 {
+		return s.parseDocuments(documents, text)
+	}
+	result := s.unquoteTopLevel(documents[0].text)
+	if token, ok, err := s.decodeJWT(result, ""); ok || err != nil {
+		if err != nil {
+			return "", err
+		}
+		return s.marshal(token)
+	}
+
+	if !s.valid(result) {
+		documents = splitDocuments(result)
+		if len(documents) > 1 {
+			return s.parseDocuments(documents, result)
+		}
+		// Anything starting with a bracket or quote isn't embedded in other text, so there's no need to look.
+		if result != "" && !strings.ContainsRune(`{["'`, rune(result[0])) {
+			regions, err := s.findEmbedded(result)
+			if err != nil {
+				return "", err
+			}
+			if isEmbeddedText(result, regions) {
+				return s.parseEmbedded(result, regions)
+			}
+		}
+	}
+
+	data, err := s.parseDocument(result, s.locate(result))
+	if err != nil && !isTruncated(err) {
+		return "", err
+	}
+	output, marshalErr := s.marshal(data)
+	if marshalErr != nil {
+		return "", marshalErr
+	}
+	return output, err
+}
+
+// valid is `json.Valid`, but remembers the answer for the last text it was asked about, as we tend to check the same
+// text a couple of times on the way through, and it's a copy of the whole thing each time.
+func (s *parseState) valid(text string) bool {
+	if !s.validChecked || text != s.validText {
+		s.validText = text
+		s.validResult = json.Valid([]byte(text))
+		s.validChecked = true
+	}
+	return s.validResult
+}
+
+// locate finds where a piece of text we've sliced (or stripped) out of the input sits in the original, so the
+// offsets we report line up with what the user gave us.  If we've changed it too much to find, we go with 0.
+func (s *parseState) locate(text string) int {
+	index := strings.Index(s.input, text)
+	if index < 0 {
+		return 0
+	}
+	return index
+}
+
+// parseDocument repairs and unmarshals a single top level value.  The offset is where it starts in the input.  If
+// it was cut off, we hand back what there was of it along with a TruncatedError.
+func (s *parseState) parseDocument(input string, offset int) (interface{}, error) {
+	if err := checkDegenerate(input); err != nil {
+		return nil, err
+	}
+	result := input
+
+	truncated := false
+	if s.Truncation != TruncationOff {
+		closed, ok, err := s.closeTruncated(result)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			s.report(DiagnosticTruncated, "", offset+len(input), offset+len(input),
+				"input was cut off, the open structures were closed and the data is incomplete")
+			kept := commonPrefixLength(input, closed)
+			s.noteRepair(RepairClosed, offset+kept, offset+len(input), input[kept:], closed[kept:])
+			result = closed
+			truncated = true
+		}
+	}
+
+	// Valid JSON doesn't need repairing, and the repair would only mangle it, taking the spaces out of its strings
+	// and turning `"42"` into `42`.  That goes for DynamoDB output too, whose typed strings (`{"N": "5"}`) we
+	// unwrap afterwards.
+	var aligned []alignedAnchor
+	repaired := false
+	start := offset
+	if IsComplexObject(result) && !s.valid(result) {
+		untrimmed := result
 		// Trim these off the top since it's just going to throw us off later.
-		result = targetStartEndOfLineQuotes.ReplaceAllString(result, "")
+		result = trimEndQuotes(result)
 		result = strings.Trim(result, "{")
 		result = strings.Trim(result, "}")
 		result = strings.Trim(result, "[")
 		result = strings.Trim(result, "]")
+		start = offset + strings.Index(untrimmed, result)
 
-		runeSlice := []rune(result)
-		isObj, err := determineObjectType(runeSlice)
+		isObj, err := determineObjectType([]rune(result))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		s.repairOffset = start
+		isObj = s.decideContainer(untrimmed, result, isObj, start, offset+len(input))
 
-		filtered, _, err := correctInvalidFormatting(isObj, runeSlice)
+		filtered, anchors, err := s.repairText(isObj, result, start, offset+len(input))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		result = filtered
+		aligned = anchors
+		repaired = true
 	}
 
-	data, err := RecursiveUnmarshal(result)
+	mark := len(s.diagnostics)
+	data, err := s.recursiveUnmarshal(result, offset)
+	if repaired {
+		// What we found in the repaired text is better placed back in the input, where the user can find it.
+		for i := mark; i < len(s.diagnostics); i++ {
+			d := &s.diagnostics[i]
+			if d.Start >= 0 {
+				d.Start = mapRepaired(aligned, d.Start-offset, start)
+				d.End = mapRepaired(aligned, d.End-offset, start)
+			}
+		}
+		var duplicate *DuplicateKeyError
+		if errors.As(err, &duplicate) && duplicate.Start >= 0 {
+			duplicate.Start = mapRepaired(aligned, duplicate.Start-offset, start)
+		}
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if s.NormalizeNumbers {
+		data = normalizeNumbers(data)
+	}
+	if !truncated {
+		return data, nil
+	}
+	if dict, ok := data.(map[string]interface{}); ok && s.Truncation == TruncationMark {
+		dict[truncatedMarker] = true
 	}
+	return data, &TruncatedError{Offset: offset + len(input)}
+}
 
+func marshalIndent(data interface{}) (string, error) {
 	resultBytes, err := json.MarshalIndent(data, "", "    ")
 	if err != nil {
 		return "", err
@@ -54,6 +308,10 @@ func Parse(input string) (string, error) {
 func determineObjectType(input []rune) (isObject bool, err error) {
 	isObject = false
 	err = nil
+	if len(input) == 0 {
+		err = &DegenerateInputError{}
+		return
+	}
 
 	firstChar := input[0]
 	if firstChar == '{' {
@@ -73,31 +331,58 @@ func determineObjectType(input []rune) (isObject bool, err error) {
 
 func format(input string) string {
 	result := strings.TrimSpace(input)
-	result = targetStartEndOfLineQuotes.ReplaceAllString(result, "")
-	result = targetStartEndResidualSlashes.ReplaceAllString(result, "")
+	// Also strip just lingering `\` characters at the start and end of the line.
+	// We may have skipped the `"` characters during processing.
+	result = trimEndQuotes(result)
+	result = strings.Trim(result, `\`)
 
-	if result == "true" || result == "false" || result == "null" || IsNumber(result) || IsComplexObject(input) {
+	// Only numbers JSON takes can be left bare, anything like `007` is kept as it was, as a string.
+	if result == "true" || result == "false" || result == "null" || isJSONNumber(result) || IsComplexObject(input) {
 		return result
 	}
-	return fmt.Sprintf(`"%s"`, result)
+	return `"` + result + `"`
 }
 
-func correctInvalidFormatting(isObj bool, input []rune) (result string, processedIndex int, err error) {
+// correctInvalidFormatting rewrites the input as JSON.  The base is where the input starts in the runes we were
+// first called with, so the repairs we record line up with them.
+func (s *parseState) correctInvalidFormatting(
+	isObj bool, input []rune, base int,
+) (result string, processedIndex int, err error) {
 	result = ""
-	// If we're just starting, we want to make sure we start processing normally,
-	//  and don't skip in the index compare below.
+	// processedIndex is the last index we've processed, which is how far ahead the caller skips once we're done.
 	processedIndex = -1
 	err = nil
 
 	key := strings.Builder{}
 	value := strings.Builder{}
-	current := strings.Builder{}
+	// current is the key or value we're partway through.  It's a slice rather than a builder so we can reuse it
+	// for each one.
+	var current []byte
 	processedData := strings.Builder{}
+	if base == 0 {
+		// Everything ends up in here, so start it at about the size it'll end up.
+		processedData.Grow(len(input))
+	}
 	seenSemiColon := false
-
-	for i, r := range input {
-		// We may have processed recursively ahead, we need to skip that piece before we can start again.
-		if i <= processedIndex || isSkippableCharacter(r) {
+	// quoted is whether the text in `current` had quotes around it, so we know `"None"` is meant as a string.
+	quoted := false
+	// We keep track of where `current` came from, and the last comma, so we can report what we changed.
+	span := tokenSpan{start: -1}
+	lastComma := -1
+
+	for i := 0; i < len(input); i++ {
+		r := input[i]
+		if i%cancelCheckInterval == 0 {
+			if err = s.cancelled(); err != nil {
+				return
+			}
+		}
+		if isSkippableCharacter(r) {
+			if r == '"' || r == '\'' {
+				quoted = true
+				span.extend(base + i)
+				lastComma = -1
+			}
 			continue
 		}
 		processedIndex = i
@@ -109,14 +394,17 @@ func correctInvalidFormatting(isObj bool, input []rune) (result string, processe
 				err = e
 				return
 			}
-			nestedData, nestedIndex, e := correctInvalidFormatting(nestedIsObj, nestedInput)
+			nestedData, nestedIndex, e := s.correctInvalidFormatting(nestedIsObj, nestedInput, base+i+1)
 			if e != nil {
 				err = e
 				return
 			}
-			current.WriteString(nestedData)
-			// Start processing on the index after this.
+			current = append(current, nestedData...)
+			span.nested = true
+			lastComma = -1
+			// We've processed recursively ahead, so start processing again on the index after that.
 			processedIndex += nestedIndex + 1
+			i = processedIndex
 			continue
 		} else if endsComplexDataStructure(r) {
 			// We will ditch the opening tags by way of the above if condition.
@@ -125,19 +413,25 @@ func correctInvalidFormatting(isObj bool, input []rune) (result string, processe
 			break
 		}
 		if r == ':' {
-			key.WriteString(format(current.String()))
-			current = strings.Builder{}
+			formatted := format(string(current))
+			s.noteToken(span, base+i, formatted, quoted)
+			key.WriteString(formatted)
+			current = current[:0]
 			seenSemiColon = true
+			quoted = false
+			span = tokenSpan{start: -1}
 			continue
 		}
 		if r == ',' {
-			value.WriteString(format(current.String()))
-			processedData = writeLine(key, value, processedData)
-
-			key = strings.Builder{}
-			value = strings.Builder{}
-			current = strings.Builder{}
+			formatted := formatValue(string(current), quoted, s.literals)
+			s.noteToken(span, base+i, formatted, quoted)
+			value.WriteString(formatted)
+			writeLine(&key, &value, &processedData)
+			current = current[:0]
 			seenSemiColon = false
+			quoted = false
+			span = tokenSpan{start: -1}
+			lastComma = base + i
 			continue
 		}
 		if r == '\n' {
@@ -151,28 +445,39 @@ func correctInvalidFormatting(isObj bool, input []rune) (result string, processe
 			// line's value.  That means we can't interpret this `\n` as a key/value pair break, and we need to
 			// throw out this newline character and continue processing.
 			// `i+1` is required to start searching after the current character which is already a `\n`
-			nextAnchor := findNextAnchorCharacter(input[i+1:])
-			if nextAnchor != ':' {
+			nextAnchor, textAfter := findNextAnchorCharacter(input[i+1:])
+			// Either way is a guess, so we keep track of it in case we guessed wrong.
+			if !s.decideLineBreak(base+i, string(current), quoted, textAfter, nextAnchor == ':') {
 				continue
 			}
 
-			value.WriteString(format(current.String()))
-			processedData = writeLine(key, value, processedData)
-
-			key = strings.Builder{}
-			value = strings.Builder{}
-			current = strings.Builder{}
+			formatted := formatValue(string(current), quoted, s.literals)
+			s.noteToken(span, base+i, formatted, quoted)
+			s.noteRepair(RepairInsertedComma, base+i, base+i, "", ",")
+			value.WriteString(formatted)
+			writeLine(&key, &value, &processedData)
+			current = current[:0]
 			seenSemiColon = false
+			quoted = false
+			span = tokenSpan{start: -1}
 			continue
 		}
-		current.WriteRune(r)
+		current = utf8.AppendRune(current, r)
+		span.extend(base + i)
+		lastComma = -1
 	}
 
 	// Catch the end of the processing
 	// NOTE: this is the last one, so we will take out the commas (that's why this isn't shared with the above saving)
-	if current.Len() > 0 {
-		value.WriteString(format(current.String()))
-		processedData = writeLine(key, value, processedData)
+	// A key with nothing after it still gets its (empty) value, and so does an empty string.
+	if len(current) > 0 || seenSemiColon || quoted {
+		formatted := formatValue(string(current), quoted, s.literals)
+		s.noteToken(span, base+processedIndex+1, formatted, quoted)
+		value.WriteString(formatted)
+		writeLine(&key, &value, &processedData)
+	}
+	if lastComma >= 0 {
+		s.noteRepair(RepairTrailingComma, lastComma, lastComma+1, ",", "")
 	}
 
 	result = processedData.String()
@@ -187,114 +492,110 @@ func correctInvalidFormatting(isObj bool, input []rune) (result string, processe
 	return
 }
 
-// RecursiveUnmarshal takes a JSON byte array and recursively unmarshals it into a nested map or slice.
-func RecursiveUnmarshal(data string) (interface{}, error) {
-	var result interface{}
+// RecursiveUnmarshal takes a JSON string and recursively unmarshals it into a nested map or slice.
+func RecursiveUnmarshal(data string) (result interface{}, err error) {
+	defer recoverPanic(&err)
+	s := &parseState{Parser: &Parser{}, input: data}
+	return s.recursiveUnmarshal(data, 0)
+}
 
+func (s *parseState) recursiveUnmarshal(data string, offset int) (interface{}, error) {
 	// Attempt to unmarshal the JSON into an empty interface
-	err := json.Unmarshal([]byte(data), &result)
+	result, err := s.decode(data, offset, "")
 	if err != nil {
 		return nil, err
 	}
 
+	// Unwrap any DynamoDB typed data first, so the natural values get the same treatment as everything else.
+	if !s.DynamoDB.Disabled {
+		result = unwrapDynamoDB(result, "", func(path string) {
+			s.report(DiagnosticDynamoDB, path, -1, -1, "unwrapped DynamoDB attribute values into plain JSON")
+		})
+	}
 	// Process the unmarshaled data recursively
-	result = processRecursively(result)
-
-	return result, nil
+	return s.processRecursively(result, "")
 }
 
 // processRecursively handles maps and slices recursively to ensure all values are processed.
-func processRecursively(input interface{}) interface{} {
+func (s *parseState) processRecursively(input interface{}, path string) (interface{}, error) {
+	if err := s.cancelled(); err != nil {
+		return nil, err
+	}
 	switch value := input.(type) {
 	case map[string]interface{}: // Process a JSON object
-		value = handleDotNotation(value)
-		for key, val := range value {
-			mapVal, ok := val.(map[string]interface{})
-			if ok {
-				value[key] = processRecursively(mapVal)
-			}
-			arrVal, ok := val.([]interface{})
-			if ok {
-				value[key] = processRecursively(arrVal)
+		value, err := s.handleDotNotation(value, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range s.orderedKeys(value) {
+			if s.untouched(value[key]) {
+				continue
 			}
-			strVal, ok := val.(string)
-			if ok {
-				if strings.HasPrefix(strVal, "{") {
-					var tmp map[string]interface{}
-					err := json.Unmarshal([]byte(strVal), &tmp)
-					if err == nil {
-						result := processRecursively(tmp)
-						value[key] = result
-					}
-				}
-				if strings.HasPrefix(strVal, "[") {
-					var tmp []interface{}
-					err := json.Unmarshal([]byte(strVal), &tmp)
-					if err == nil {
-						result := processRecursively(tmp)
-						value[key] = result
-					}
-				}
+			result, err := s.processValue(value[key], joinPath(path, key))
+			if err != nil {
+				return nil, err
 			}
+			value[key] = result
 		}
-		return value
+		return value, nil
 	case []interface{}: // Process a JSON array
 		for i, val := range value {
-			mapVal, ok := val.(map[string]interface{})
-			if ok {
-				value[i] = processRecursively(mapVal)
-			}
-			arrVal, ok := val.([]interface{})
-			if ok {
-				value[i] = processRecursively(arrVal)
+			if s.untouched(val) {
+				continue
 			}
-			strVal, ok := val.(string)
-			if ok {
-				if strings.HasPrefix(strVal, "{") {
-					var tmp map[string]interface{}
-					err := json.Unmarshal([]byte(strVal), &tmp)
-					if err == nil {
-						result := processRecursively(tmp)
-						value[i] = result
-					}
-				}
-				if strings.HasPrefix(strVal, "[") {
-					var tmp []interface{}
-					err := json.Unmarshal([]byte(strVal), &tmp)
-					if err == nil {
-						result := processRecursively(tmp)
-						value[i] = result
-					}
-				}
+			result, err := s.processValue(val, indexPath(path, i))
+			if err != nil {
+				return nil, err
 			}
+			value[i] = result
 		}
-		return value
+		return value, nil
 	default: // Base case: return the value as is for primitive types
-		return value
+		return value, nil
 	}
 }
 
-func handleDotNotation(data map[string]interface{}) map[string]interface{} {
-	result := map[string]interface{}{}
-	for key, value := range data {
-		if key == "" {
-			continue
-		}
-		setNestedValue(result, key, value)
+// untouched reports whether processValue would hand the value back as it is, so we can skip working out its path.
+// That's any number, boolean or null, unless we're coercing them.
+func (s *parseState) untouched(value interface{}) bool {
+	if s.Coercion.Mode != CoerceOff {
+		return false
 	}
-	return result
+	switch value.(type) {
+	case json.Number, bool, nil, float64:
+		return true
+	}
+	return false
 }
 
-func setNestedValue(data map[string]interface{}, path string, value interface{}) {
-	keys := strings.Split(path, ".")
-	current := data
+// processValue handles a single value out of an object or array, unpacking any JSON we find stored in a string.
+func (s *parseState) processValue(input interface{}, path string) (interface{}, error) {
+	if coerced, ok := s.coerce(input, path); ok {
+		return coerced, nil
+	}
+	strVal, ok := input.(string)
+	if !ok {
+		return s.processRecursively(input, path)
+	}
+	token, ok, err := s.decodeJWT(strVal, path)
+	if ok || err != nil {
+		return token, err
+	}
+	data, steps, ok := s.unwrapString(strVal, path)
+	if !ok {
+		return strVal, nil
+	}
 
-	for _, key := range keys[:len(keys)-1] {
-		if _, ok := current[key]; !ok {
-			current[key] = make(map[string]interface{})
-		}
-		current = current[key].(map[string]interface{})
+	s.report(DiagnosticDecoded, path, -1, -1, "decoded JSON stored in a string (%s)", strings.Join(steps, ", "))
+	s.stringDepth += len(steps)
+	result, err := s.processRecursively(data, path)
+	s.stringDepth -= len(steps)
+	if err != nil || !s.StringDecoding.KeepOriginal {
+		return result, err
 	}
 
-	current[keys[len(keys)-1]] = value
+	both := map[string]interface{}{}
+	s.setKey(both, originalKey, strVal)
+	s.setKey(both, decodedKey, result)
+	return both, nil
 }
@@ -429,9 +429,10 @@ func TestParse_success_strips_trailing_commas(t *testing.T) {
 
 func TestParse_success_strips_extra_white_spaces(t *testing.T) {
 	// Root and nested
+	// It's valid JSON, so the string stays a string.
 	input := "{\"test\": \"1\",\n\n\n\n\"test2\"\t\t\n: 2,\t\t\t\t\"test3\"\n\n\t: true}"
 	expected := `{
-    "test": 1,
+    "test": "1",
     "test2": 2,
     "test3": true
 }`
@@ -0,0 +1,242 @@
+package parse
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// RepairKind is the kind of change we made to the input to turn it into JSON.
+type RepairKind string
+
+const (
+	// RepairQuoted means we put quotes around a bare key or value, eg. `name` to `"name"`.
+	RepairQuoted RepairKind = "quoted"
+	// RepairSingleQuotes means we swapped single quotes for double quotes.
+	RepairSingleQuotes RepairKind = "single_quotes"
+	// RepairUnquoted means we took the quotes off a value, eg. `"42"` to `42`.
+	RepairUnquoted RepairKind = "unquoted"
+	// RepairLiteral means we read a bare word as null or a boolean, eg. `None` to `null`.
+	RepairLiteral RepairKind = "literal"
+	// RepairEmptyValue means a key had nothing after it, so we filled in a value.
+	RepairEmptyValue RepairKind = "empty_value"
+	// RepairInsertedComma means a newline was taken as the end of a value, where there should have been a comma.
+	RepairInsertedComma RepairKind = "inserted_comma"
+	// RepairTrailingComma means we dropped a comma with nothing after it.
+	RepairTrailingComma RepairKind = "trailing_comma"
+	// RepairClosed means the input was cut off, and we closed whatever was left open.
+	RepairClosed RepairKind = "closed"
+	// RepairRewritten is any other change to a key or value, like whitespace we took out of it.
+	RepairRewritten RepairKind = "rewritten"
+)
+
+// Repair is a single change we made to the input, to get it to parse.
+type Repair struct {
+	Kind RepairKind
+	// Start and End are the byte offsets of what we replaced in the input, see `Diagnostic` for what they're
+	// offsets into.  They're the same when we only added something.
+	Start       int
+	End         int
+	Original    string
+	Replacement string
+}
+
+func (r Repair) String() string {
+	switch {
+	case r.Original == "":
+		return fmt.Sprintf("%s at %d: added %s", r.Kind, r.Start, r.Replacement)
+	case r.Replacement == "":
+		return fmt.Sprintf("%s at %d: removed %s", r.Kind, r.Start, r.Original)
+	default:
+		return fmt.Sprintf("%s at %d: %s to %s", r.Kind, r.Start, r.Original, r.Replacement)
+	}
+}
+
+// tokenSpan is where a key or value came from in the runes being repaired, including any quotes around it.
+type tokenSpan struct {
+	start, end int
+	// nested is true if it holds an object or array, which will have had its own repairs recorded.
+	nested bool
+}
+
+func (t *tokenSpan) extend(index int) {
+	if t.start < 0 {
+		t.start = index
+	}
+	t.end = index + 1
+}
+
+// repairAnchor is a key or value we wrote out while repairing, and where it came from.  Lining these up with the
+// repaired text lets us map offsets in it back to the input.
+type repairAnchor struct {
+	output     string
+	start, end int
+}
+
+// alignedAnchor is a repairAnchor, found in the repaired text.  Offsets are in bytes.
+type alignedAnchor struct {
+	repaired, input, length int
+}
+
+// noteToken records how a key or value was changed by the repair, if it was at all.  The index is where an empty
+// value would have been.
+func (s *parseState) noteToken(span tokenSpan, index int, formatted string, quoted bool) {
+	if span.nested {
+		return
+	}
+	if span.start < 0 {
+		s.noteRepair(RepairEmptyValue, index, index, "", formatted)
+		return
+	}
+	s.anchors = append(grow(s.anchors), repairAnchor{output: formatted, start: span.start, end: span.end})
+
+	var raw string
+	if s.repairASCII != "" {
+		raw = s.repairASCII[span.start:span.end]
+	} else {
+		raw = string(s.repairInput[span.start:span.end])
+	}
+	inner := raw
+	if len(raw) >= 2 && strings.ContainsRune(`"'`, rune(raw[0])) && raw[len(raw)-1] == raw[0] {
+		inner = raw[1 : len(raw)-1]
+	}
+
+	var kind RepairKind
+	switch {
+	case raw == formatted:
+		return
+	case raw[0] == '\'' && isQuotedAs(formatted, inner):
+		kind = RepairSingleQuotes
+	case isQuotedAs(formatted, raw):
+		kind = RepairQuoted
+	case inner != raw && formatted == inner:
+		kind = RepairUnquoted
+	case !quoted && (formatted == "null" || formatted == "true" || formatted == "false"):
+		kind = RepairLiteral
+	default:
+		kind = RepairRewritten
+	}
+	s.noteRepair(kind, span.start, span.end, raw, formatted)
+}
+
+// isQuotedAs is whether the text is the inner text in double quotes.
+func isQuotedAs(text, inner string) bool {
+	return len(text) == len(inner)+2 && text[0] == '"' && text[len(text)-1] == '"' && text[1:len(text)-1] == inner
+}
+
+// noteRepair records a change.  While we're repairing, the offsets are indexes into the runes being repaired,
+// `repairText` turns them into offsets into the input afterwards.
+func (s *parseState) noteRepair(kind RepairKind, start, end int, original, replacement string) {
+	s.repairs = append(grow(s.repairs), Repair{
+		Kind:        kind,
+		Start:       start,
+		End:         end,
+		Original:    original,
+		Replacement: replacement,
+	})
+}
+
+// grow doubles the slice's capacity once it's full.  `append` only adds a quarter once a slice gets big, and there's a
+// repair for every few bytes of input, so we'd spend a lot of the time copying them from one slice to the next.
+func grow[S ~[]E, E any](slice S) S {
+	if len(slice) < cap(slice) {
+		return slice
+	}
+	return slices.Grow(slice, max(len(slice), minRepairs))
+}
+
+// minRepairs is the fewest repairs we make room for at once.
+const minRepairs = 16
+
+// repairText runs the repair over text that starts at the offset in the input, and records what it changed
+// along the way.  It returns the repaired text, and the anchors to map offsets in it back to the input with.
+// Nothing is placed past the limit, so closers we added to truncated input are put at the end of it.
+func (s *parseState) repairText(isObj bool, text string, offset, limit int) (string, []alignedAnchor, error) {
+	s.repairInput = []rune(text)
+	s.repairASCII = ""
+	if len(text) == len(s.repairInput) {
+		s.repairASCII = text
+	}
+	s.anchors = s.anchors[:0]
+	s.repairOffset = offset
+	mark := len(s.repairs)
+	ambiguityMark := len(s.ambiguities)
+	if s.literals == nil {
+		s.literals = s.Literals.table()
+	}
+
+	repaired, _, err := s.correctInvalidFormatting(isObj, s.repairInput, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Byte offsets into the input for each rune, with one more for the end.  We can skip working them out when
+	// it's all ASCII, which is most of the time, as they're the same as the rune indexes.
+	var offsets []int
+	if len(text) != len(s.repairInput) {
+		offsets = make([]int, len(s.repairInput)+1)
+		for i, r := range s.repairInput {
+			offsets[i+1] = offsets[i] + utf8.RuneLen(r)
+		}
+	}
+	toInput := func(index int) int {
+		if index > len(s.repairInput) {
+			index = len(s.repairInput)
+		}
+		if offsets != nil {
+			index = offsets[index]
+		}
+		if offset+index > limit {
+			return limit
+		}
+		return offset + index
+	}
+
+	for i := mark; i < len(s.repairs); i++ {
+		s.repairs[i].Start = toInput(s.repairs[i].Start)
+		s.repairs[i].End = toInput(s.repairs[i].End)
+	}
+	for i := ambiguityMark; i < len(s.ambiguities); i++ {
+		s.ambiguities[i].Start = toInput(s.ambiguities[i].Start)
+		s.ambiguities[i].End = toInput(s.ambiguities[i].End)
+	}
+
+	// The keys and values come out of the repair in the same order they went in, so we can find each one after
+	// the last.
+	aligned := make([]alignedAnchor, 0, len(s.anchors))
+	cursor := 0
+	for _, anchor := range s.anchors {
+		index := strings.Index(repaired[cursor:], anchor.output)
+		if index < 0 {
+			continue
+		}
+		start := toInput(anchor.start)
+		aligned = append(aligned, alignedAnchor{
+			repaired: cursor + index,
+			input:    start,
+			length:   toInput(anchor.end) - start,
+		})
+		cursor += index + len(anchor.output)
+	}
+	s.repairInput = nil
+	s.repairASCII = ""
+	return repaired, aligned, nil
+}
+
+// mapRepaired turns an offset into the repaired text into an offset into the input, going by the closest key or
+// value before it.  If there isn't one, it's the fallback.
+func mapRepaired(aligned []alignedAnchor, offset, fallback int) int {
+	i := sort.Search(len(aligned), func(i int) bool {
+		return aligned[i].repaired > offset
+	}) - 1
+	if i < 0 {
+		return fallback
+	}
+	delta := offset - aligned[i].repaired
+	if delta > aligned[i].length {
+		delta = aligned[i].length
+	}
+	return aligned[i].input + delta
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_ParseResult_success_reports_repairs(t *testing.T) {
+	input := "{name: 'jane', age: \"42\", ok: None,\n\"tags\": [1, 2,]}"
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Repair{
+		{Kind: RepairQuoted, Start: 1, End: 5, Original: "name", Replacement: `"name"`},
+		{Kind: RepairSingleQuotes, Start: 7, End: 13, Original: "'jane'", Replacement: `"jane"`},
+		{Kind: RepairQuoted, Start: 15, End: 18, Original: "age", Replacement: `"age"`},
+		{Kind: RepairUnquoted, Start: 20, End: 24, Original: `"42"`, Replacement: "42"},
+		{Kind: RepairQuoted, Start: 26, End: 28, Original: "ok", Replacement: `"ok"`},
+		{Kind: RepairLiteral, Start: 30, End: 34, Original: "None", Replacement: "null"},
+		{Kind: RepairTrailingComma, Start: 49, End: 50, Original: ",", Replacement: ""},
+	}, result.Repairs)
+}
+
+func TestParser_ParseResult_success_reports_inserted_commas_and_empty_values(t *testing.T) {
+	input := "a: 1\nb: 2\nc:"
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Repair{
+		{Kind: RepairQuoted, Start: 0, End: 1, Original: "a", Replacement: `"a"`},
+		{Kind: RepairInsertedComma, Start: 4, End: 4, Original: "", Replacement: ","},
+		{Kind: RepairQuoted, Start: 5, End: 6, Original: "b", Replacement: `"b"`},
+		{Kind: RepairInsertedComma, Start: 9, End: 9, Original: "", Replacement: ","},
+		{Kind: RepairQuoted, Start: 10, End: 11, Original: "c", Replacement: `"c"`},
+		{Kind: RepairEmptyValue, Start: 12, End: 12, Original: "", Replacement: "null"},
+	}, result.Repairs)
+	assert.Equal(t, "inserted_comma at 4: added ,", result.Repairs[1].String())
+}
+
+func TestParser_ParseResult_success_reports_closed_truncated_input(t *testing.T) {
+	input := `{"a": [1, {"b": 2`
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, []Repair{
+		{Kind: RepairClosed, Start: 17, End: 17, Original: "", Replacement: "}]}"},
+	}, result.Repairs)
+}
+
+func TestParser_ParseResult_success_nothing_to_repair(t *testing.T) {
+	input := `{"a": 1, "b": [1, 2.5, true, null], "c": {"d": "e"}}`
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Empty(t, result.Repairs)
+}
+
+func TestParser_ParseResult_success_maps_diagnostics_back_to_the_input(t *testing.T) {
+	input := `{a: 1, b: {c: 2, c: 3}}`
+
+	result, err := (&Parser{}).ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, DiagnosticDuplicateKey, result.Diagnostics[0].Kind)
+	assert.Equal(t, "c", input[result.Diagnostics[0].Start:result.Diagnostics[0].End])
+	assert.Equal(t, 17, result.Diagnostics[0].Start)
+}
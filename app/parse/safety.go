@@ -0,0 +1,48 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// DegenerateInputError is returned for input there's nothing in to make JSON out of, like an empty paste or a
+// lone `:`.
+type DegenerateInputError struct {
+	Input string
+}
+
+func (e *DegenerateInputError) Error() string {
+	if strings.TrimSpace(e.Input) == "" {
+		return "nothing to parse, the input is empty"
+	}
+	return fmt.Sprintf("nothing to parse, %q is only brackets and punctuation", e.Input)
+}
+
+// checkDegenerate makes sure there's something to parse in the input, which has to have more than brackets,
+// quotes and separators in it, unless it's already valid JSON like `{}` or `""`.
+func checkDegenerate(input string) error {
+	if strings.Trim(input, "{}[]:,\"' \t\r\n") != "" || json.Valid([]byte(input)) {
+		return nil
+	}
+	return &DegenerateInputError{Input: input}
+}
+
+// PanicError is what we return if we hit a bug that would have panicked, so a bad paste can't take down
+// whatever is calling us.  It's always a bug on our end, so it has the stack to report it with.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("unexpected error while parsing, please report it: %v", e.Value)
+}
+
+// recoverPanic turns a panic into a PanicError.  It has to be deferred by the function whose error it sets.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
@@ -0,0 +1,75 @@
+package parse
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_failure_degenerate_input(t *testing.T) {
+	for _, input := range []string{"", "  \n\t", ":", ",", "{:}", "[,]", "'", `{"":}`} {
+		_, err := Parse(input)
+
+		var degenerate *DegenerateInputError
+		assert.True(t, errors.As(err, &degenerate), "%q: %v", input, err)
+	}
+
+	_, err := Parse(":")
+	assert.Equal(t, `nothing to parse, ":" is only brackets and punctuation`, err.Error())
+	_, err = Parse("")
+	assert.Equal(t, "nothing to parse, the input is empty", err.Error())
+}
+
+func TestParse_success_barely_anything(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{}", "{}"},
+		{"[]", "[]"},
+		{`""`, `""`},
+		{"[[]]", "[[]]"},
+		{`{"": 1}`, `{"":1}`},
+		{"a..b: 1", `{"a":{"":{"b":1}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := Parse(tt.input, WithCompact())
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParse_success_valid_json_is_left_as_is(t *testing.T) {
+	input := `{"a": "hello world", "b": "42", "c": "it's", "d": "x:y", "e": ["a, b"], "f": "\"q\""}`
+
+	result, err := Parse(input, WithCompact(), WithKeyOrder(KeysInputOrder))
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":"hello world","b":"42","c":"it's","d":"x:y","e":["a, b"],"f":"\"q\""}`, result)
+}
+
+func TestDetermineObjectType_failure_empty(t *testing.T) {
+	_, err := determineObjectType([]rune{})
+
+	var degenerate *DegenerateInputError
+	assert.True(t, errors.As(err, &degenerate))
+}
+
+func TestRecoverPanic(t *testing.T) {
+	run := func() (err error) {
+		defer recoverPanic(&err)
+		var data map[string]interface{}
+		data["a"] = 1
+		return nil
+	}
+
+	err := run()
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Contains(t, err.Error(), "assignment to entry in nil map")
+	assert.NotEmpty(t, panicErr.Stack)
+}
@@ -0,0 +1,339 @@
+package parse
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Decoder reads documents one at a time out of a stream, like a multi hundred MB NDJSON export, so only the
+// document we're on has to be in memory.  A document ends at the end of a line where every object and array in it
+// has been closed, so NDJSON is read a line at a time, and pretty printed documents a document at a time.  Loose
+// input that isn't wrapped in brackets (`a: 1` lines) is read a line at a time as well, so it has to be one
+// document per line.
+type Decoder struct {
+	parser *Parser
+	reader *bufio.Reader
+	// pending is the documents we've read, but not handed out yet.
+	pending []streamedDocument
+	// index is how many documents we've handed out, and line is how many lines we've read.
+	index int
+	line  int
+	err   error
+	// held is a line we read ahead, to see if it carried on the one before, along with the error reading it.  It
+	// hasn't been counted in line yet.
+	held    string
+	heldErr error
+}
+
+// streamedDocument is a document we've read, the line it starts on, and where it is in the stream.
+type streamedDocument struct {
+	text  string
+	line  int
+	index int
+}
+
+// NewDecoder makes a Decoder reading from the reader, with the options applied.
+func NewDecoder(reader io.Reader, options ...Option) *Decoder {
+	return NewParser(options...).NewDecoder(reader)
+}
+
+// NewDecoder makes a Decoder reading from the reader, with the Parser's settings.
+func (p *Parser) NewDecoder(reader io.Reader) *Decoder {
+	return &Decoder{parser: p, reader: bufio.NewReader(reader)}
+}
+
+// Decode reads and parses the next document.  It returns io.EOF once there aren't any left.  A document that
+// couldn't be parsed comes back as a DocumentError, and Decode can be called again to carry on with the next one.
+// One that was cut off comes back as far as it goes, along with a DocumentError holding a TruncatedError.
+func (d *Decoder) Decode() (interface{}, error) {
+	data, _, err := d.decode()
+	return data, err
+}
+
+// decode is Decode, but also hands back the state the document was parsed with, so it can be written out in the
+// order its keys were read.
+func (d *Decoder) decode() (interface{}, *parseState, error) {
+	doc, err := d.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.parser.parseStreamed(doc)
+}
+
+// next reads the next document, without parsing it.
+func (d *Decoder) next() (doc streamedDocument, err error) {
+	defer recoverPanic(&err)
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return streamedDocument{}, d.err
+		}
+		d.read()
+	}
+
+	doc = d.pending[0]
+	d.pending = d.pending[1:]
+	doc.index = d.index
+	d.index++
+	return doc, nil
+}
+
+// parseStreamed parses a document we've read.  Each document gets a state of its own, so what we remember about
+// one doesn't pile up over the whole stream, and documents can be parsed at the same time as each other.
+func (p *Parser) parseStreamed(doc streamedDocument) (data interface{}, s *parseState, err error) {
+	defer recoverPanic(&err)
+	s = &parseState{Parser: p, input: doc.text}
+	err = s.checkLimits(doc.text)
+	if err != nil {
+		return nil, nil, &DocumentError{Index: doc.index, Line: doc.line, Err: err}
+	}
+	data, err = s.parseDocument(s.unquoteTopLevel(doc.text), 0)
+	if isTruncated(err) {
+		return data, s, &DocumentError{Index: doc.index, Line: doc.line, Err: err}
+	}
+	if err != nil {
+		return nil, nil, &DocumentError{Index: doc.index, Line: doc.line, Err: err}
+	}
+	return data, s, nil
+}
+
+// read reads up to the end of the next document, and splits it up if there's more than one in there
+// (`{"a":1}{"a":2}`).
+//
+// A line of NDJSON that was cut off never closes, which would have us read every line after it into the same
+// document, up to the end of the stream.  So if a document's first line doesn't close, and couldn't carry on into
+// the next one, we take it the first line was cut off, and end the document there.  See `cutOff`.
+func (d *Decoder) read() {
+	text := strings.Builder{}
+	start := d.line + 1
+	lines := 0
+	depth := 0
+	inString := false
+	escaped := false
+
+	for {
+		line, err := d.readLine()
+		if strings.TrimSpace(line) == "" && text.Len() == 0 {
+			// Skip blank lines between documents.
+			if line != "" {
+				d.line++
+				start = d.line + 1
+			}
+			if err != nil {
+				d.err = err
+				return
+			}
+			continue
+		}
+		d.line++
+		lines++
+		text.WriteString(line)
+		for _, r := range line {
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case r == '\\':
+					escaped = true
+				case r == '"':
+					inString = false
+				}
+				continue
+			}
+			switch {
+			case r == '"' && depth > 0:
+				inString = true
+			case startsComplexDataStructure(r):
+				depth++
+			case endsComplexDataStructure(r) && depth > 0:
+				depth--
+			}
+		}
+
+		if err != nil {
+			// Whatever is left is all there is, even if it was cut off partway through.
+			d.err = err
+			break
+		}
+		if depth == 0 || lines == 1 && d.cutOff(line) {
+			break
+		}
+	}
+
+	input := strings.TrimSpace(text.String())
+	if input == "" {
+		return
+	}
+	// Count the lines as we go, rather than from the top each time, as there can be a lot of documents on one.
+	line, counted := start, 0
+	for _, doc := range splitDocuments(input) {
+		line += strings.Count(input[counted:doc.offset], "\n")
+		counted = doc.offset
+		d.pending = append(d.pending, streamedDocument{text: doc.text, line: line})
+	}
+}
+
+// readLine reads the next line, or hands back the one we read ahead, if we did.
+func (d *Decoder) readLine() (string, error) {
+	if d.held != "" {
+		line, err := d.held, d.heldErr
+		d.held, d.heldErr = "", nil
+		return line, err
+	}
+	return d.reader.ReadString('\n')
+}
+
+// cutOff is whether the first line of a document, which didn't close, was cut off rather than carrying on into the
+// next line.  A line that ends after a `,`, `:`, `[` or `{` is waiting on more, so it carries on.  Otherwise it's
+// only cut off if the next line starts an object or array, and the two together aren't valid JSON, like
+// `{"a": [1,` and then `{"a": 2}`.  We hold on to the next line either way, so it's read again from there.
+func (d *Decoder) cutOff(first string) bool {
+	trimmed := strings.TrimSpace(first)
+	if len(trimmed) <= 1 || strings.IndexByte(",:[{", trimmed[len(trimmed)-1]) >= 0 {
+		return false
+	}
+	next, err := d.reader.Peek(1)
+	if err != nil || !startsComplexDataStructure(rune(next[0])) {
+		return false
+	}
+	d.held, d.heldErr = d.reader.ReadString('\n')
+	return !json.Valid([]byte(first + d.held))
+}
+
+// Encoder writes documents out to a stream, one at a time, in the Parser's DocumentFormat.
+type Encoder struct {
+	parser *Parser
+	writer io.Writer
+	count  int
+}
+
+// NewEncoder makes an Encoder writing to the writer, with the options applied.
+func NewEncoder(writer io.Writer, options ...Option) *Encoder {
+	return NewParser(options...).NewEncoder(writer)
+}
+
+// NewEncoder makes an Encoder writing to the writer, with the Parser's settings.
+func (p *Parser) NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{parser: p, writer: writer}
+}
+
+// Encode writes out a single document.  As we don't know what order the keys were read in, they're sorted, unless
+// it's KeysInputOrder and the data came through `Stream`.
+func (e *Encoder) Encode(data interface{}) (err error) {
+	defer recoverPanic(&err)
+	return e.encode(&parseState{Parser: e.parser}, data)
+}
+
+func (e *Encoder) encode(s *parseState, data interface{}) error {
+	result, err := e.format(s, data)
+	if err != nil {
+		return err
+	}
+	return e.write(result)
+}
+
+// format marshals a document the way it'll be written out, which doesn't depend on what's been written before it.
+func (e *Encoder) format(s *parseState, data interface{}) (string, error) {
+	switch e.parser.DocumentFormat {
+	case DocumentsArray:
+		result, err := s.marshal(data)
+		if err != nil || e.parser.Compact {
+			return result, err
+		}
+		// Indent it to sit inside the array, the same as if it'd been marshalled as part of it.
+		return e.parser.indent() + strings.ReplaceAll(result, "\n", "\n"+e.parser.indent()), nil
+	case DocumentsNDJSON:
+		return s.marshalCompact(data)
+	default:
+		return s.marshal(data)
+	}
+}
+
+// write writes out a formatted document, after whatever separates it from the one before.
+func (e *Encoder) write(result string) error {
+	var separator string
+	switch e.parser.DocumentFormat {
+	case DocumentsArray:
+		switch {
+		case e.parser.Compact && e.count == 0:
+			separator = "["
+		case e.parser.Compact:
+			separator = ","
+		case e.count == 0:
+			separator = "[\n"
+		default:
+			separator = ",\n"
+		}
+	case DocumentsNDJSON:
+		if e.count > 0 {
+			separator = "\n"
+		}
+	default:
+		if e.count > 0 {
+			separator = "\n\n"
+		}
+	}
+
+	_, err := io.WriteString(e.writer, separator+result)
+	if err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close finishes off the output, which for DocumentsArray means closing the array.  It doesn't close the writer.
+func (e *Encoder) Close() error {
+	if e.parser.DocumentFormat != DocumentsArray {
+		return nil
+	}
+	var closer string
+	switch {
+	case e.count == 0:
+		closer = "[]"
+	case e.parser.Compact:
+		closer = "]"
+	default:
+		closer = "\n]"
+	}
+	_, err := io.WriteString(e.writer, closer)
+	return err
+}
+
+// Stream parses every document in the reader, and writes them out to the writer as it goes, so the whole input
+// never has to be in memory at once.  Documents that couldn't be parsed are left out, and returned as
+// DocumentErrors once we've been through the rest.  Documents that were cut off are written out as far as they
+// go, and returned in the DocumentErrors as well.
+func (p *Parser) Stream(reader io.Reader, writer io.Writer) error {
+	decoder := p.NewDecoder(reader)
+	encoder := p.NewEncoder(writer)
+	var errs DocumentErrors
+	for {
+		data, s, err := decoder.decode()
+		if err == io.EOF {
+			break
+		}
+		if docErr, ok := err.(*DocumentError); ok {
+			errs = append(errs, docErr)
+			if !isTruncated(err) {
+				continue
+			}
+		} else if err != nil {
+			return err
+		}
+		err = encoder.encode(s, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := encoder.Close()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+const streamInput = `{"id": 1, "name": "a"}
+
+{"id": 2,
+  "name": "b",
+  "tags": ["x", "y"]
+}
+{"id": 3}{"id": 4}
+{'id': 5, 'name': None}
+`
+
+func TestDecoder_Decode_success(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(streamInput))
+
+	var ids []json.Number
+	for {
+		data, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		ids = append(ids, data.(map[string]interface{})["id"].(json.Number))
+	}
+
+	assert.Equal(t, []json.Number{"1", "2", "3", "4", "5"}, ids)
+}
+
+func TestDecoder_Decode_failure_carries_on_after_a_bad_document(t *testing.T) {
+	input := "{\"a\": 1}\n\nnot json at all\n{\"a\": 2}"
+	decoder := NewDecoder(strings.NewReader(input))
+
+	first, err := decoder.Decode()
+	assert.Nil(t, err)
+	assert.NotNil(t, first)
+
+	_, err = decoder.Decode()
+	var docErr *DocumentError
+	assert.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 1, docErr.Index)
+	assert.Equal(t, 3, docErr.Line)
+
+	last, err := decoder.Decode()
+	assert.Nil(t, err)
+	assert.NotNil(t, last)
+
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestParser_Stream_success(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		expected string
+	}{
+		{
+			"ndjson_in_input_order",
+			[]Option{WithDocumentFormat(DocumentsNDJSON), WithKeyOrder(KeysInputOrder)},
+			`{"id":1,"name":"a"}` + "\n" + `{"id":2,"name":"b","tags":["x","y"]}` + "\n" + `{"id":3}` + "\n" +
+				`{"id":4}` + "\n" + `{"id":5,"name":null}`,
+		},
+		{
+			"compact_array",
+			[]Option{WithDocumentFormat(DocumentsArray), WithCompact()},
+			`[{"id":1,"name":"a"},{"id":2,"name":"b","tags":["x","y"]},{"id":3},{"id":4},{"id":5,"name":null}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := bytes.Buffer{}
+
+			err := NewParser(tt.options...).Stream(strings.NewReader(streamInput), &output)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestParser_Stream_success_matches_parse(t *testing.T) {
+	input := "{\"b\": 1, \"a\": [1, 2]}\n{\"c\": {\"d\": true}}"
+	for _, format := range []DocumentFormat{DocumentsPretty, DocumentsArray, DocumentsNDJSON} {
+		p := NewParser(WithDocumentFormat(format))
+		expected, err := p.Parse(input)
+		assert.Nil(t, err)
+
+		output := bytes.Buffer{}
+		err = p.Stream(strings.NewReader(input), &output)
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, output.String())
+	}
+}
+
+func TestParser_Stream_success_closes_truncated_last_document(t *testing.T) {
+	input := "{\"a\": 1}\n{\"a\": [1, 2"
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).Stream(strings.NewReader(input), &output)
+
+	assert.Equal(t, DocumentErrors{{Index: 1, Line: 2, Err: &TruncatedError{Offset: 11}}}, err)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":[1,2]}", output.String())
+}
+
+func TestParser_Stream_failure_reports_bad_documents(t *testing.T) {
+	input := "{\"a\": 1}\nnope\n{\"a\": 2}"
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).Stream(strings.NewReader(input), &output)
+
+	var errs DocumentErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 2, errs[0].Line)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2}", output.String())
+}
+
+func TestEncoder_Encode_success_empty_array(t *testing.T) {
+	output := bytes.Buffer{}
+	encoder := NewEncoder(&output, WithDocumentFormat(DocumentsArray))
+
+	assert.Nil(t, encoder.Close())
+	assert.Equal(t, "[]", output.String())
+}
+
+func TestParser_Stream_success_closes_truncated_middle_line(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":2, \"b\": \"trunc\n{\"a\":3}\n{\"a\":4}\n"
+	output := bytes.Buffer{}
+
+	err := NewParser(WithDocumentFormat(DocumentsNDJSON)).Stream(strings.NewReader(input), &output)
+
+	var errs DocumentErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 1, errs[0].Index)
+	assert.Equal(t, 2, errs[0].Line)
+	assert.True(t, isTruncated(errs[0]))
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2,\"b\":\"trunc\"}\n{\"a\":3}\n{\"a\":4}", output.String())
+}
+
+func TestParser_Stream_success_keeps_documents_that_carry_on_to_the_next_line(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{\"a\":\n{\"b\":1}}", `{"a":{"b":1}}`},
+		{"[[1,2],\n[3,4]]", `[[1,2],[3,4]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			output := bytes.Buffer{}
+
+			err := NewParser(WithDocumentFormat(DocumentsNDJSON)).Stream(strings.NewReader(tt.input), &output)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestDecoder_Decode_success_truncated_line_ends_at_the_next_document(t *testing.T) {
+	input := "{\"a\":1}\n[1, 2\n{\"a\":3}\n"
+	decoder := NewDecoder(strings.NewReader(input))
+
+	var lines []int
+	for {
+		doc, err := decoder.next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		lines = append(lines, doc.line)
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, lines)
+}
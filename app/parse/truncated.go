@@ -0,0 +1,142 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+)
+
+// TruncationMode is what we do with input that was cut off partway through, like payloads from a logging
+// pipeline that caps line length.
+type TruncationMode int
+
+const (
+	// TruncationRepair closes off the input, and reports that it was truncated in the diagnostics.
+	TruncationRepair TruncationMode = iota
+	// TruncationMark does the same as TruncationRepair, and also adds a `__truncated: true` key to the output,
+	// if the top level value is an object.
+	TruncationMark
+	// TruncationOff leaves the input as is, for the rest of the repair to make of what it can.
+	TruncationOff
+)
+
+const truncatedMarker = "__truncated"
+
+// TruncatedError is returned along with the output when the input was cut off partway through.  We closed off
+// what was left open, so the output is still JSON, but whatever came after the cut is missing from it.
+type TruncatedError struct {
+	// Offset is where the input stopped, see `Diagnostic` for what it's an offset into.
+	Offset int
+}
+
+func (e *TruncatedError) Error() string {
+	return "the input was cut off, so the data is incomplete"
+}
+
+// isTruncated is whether the error only says the data is incomplete, so there's still output to go with it.
+func isTruncated(err error) bool {
+	var truncated *TruncatedError
+	return errors.As(err, &truncated)
+}
+
+// openStructure is an object or array we've seen open, but not close yet.
+type openStructure struct {
+	closer byte
+	// expectingKey is true for objects, until we see the `:` that ends the key.
+	expectingKey bool
+	// entryStart is just past the `{`, `[` or `,` that the current entry started after.
+	entryStart int
+}
+
+// closeTruncated finishes off input that stops partway through.  It closes an unterminated string value, drops a
+// key that never got a value, and then closes any objects and arrays left open.  It returns false if the input
+// wasn't truncated, or isn't the kind of thing we can tell is.  That includes input holding more than one top level
+// value, since it's only the one that's cut off that should be closed, once they've been split up.
+func (s *parseState) closeTruncated(input string) (string, bool, error) {
+	if input == "" || !startsComplexDataStructure(rune(input[0])) {
+		return input, false, nil
+	}
+
+	var stack []openStructure
+	inString := false
+	escaped := false
+	// Stray quotes are common in the input we get (`[1, tmp"]`), so we only treat a quote as opening a string
+	// if it's somewhere a string can start.
+	var previous byte
+	for i := 0; i < len(input); i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := s.cancelled(); err != nil {
+				return input, false, err
+			}
+		}
+		c := input[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				previous = c
+			}
+			continue
+		}
+		if isWhitespace(rune(c)) {
+			continue
+		}
+		if len(stack) == 0 && i > 0 {
+			return input, false, nil
+		}
+
+		switch c {
+		case '"':
+			if previous == 0 || strings.IndexByte("{[,:", previous) >= 0 {
+				inString = true
+			}
+		case '{':
+			stack = append(stack, openStructure{closer: '}', expectingKey: true, entryStart: i + 1})
+		case '[':
+			stack = append(stack, openStructure{closer: ']', entryStart: i + 1})
+		case '}', ']':
+			// Whichever closer it is, we'll let the repair sort out mismatches.
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			if len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				top.entryStart = i + 1
+				top.expectingKey = top.closer == '}'
+			}
+		case ':':
+			if len(stack) > 0 {
+				stack[len(stack)-1].expectingKey = false
+			}
+		}
+		previous = c
+	}
+	if len(stack) == 0 {
+		return input, false, nil
+	}
+
+	result := input
+	top := stack[len(stack)-1]
+	entry := strings.TrimSpace(input[top.entryStart:])
+	switch {
+	case top.closer == '}' && top.expectingKey && entry != "":
+		// Part of a key, or a whole key with no `:` after it.  Either way there's no value to keep.
+		result = input[:top.entryStart]
+	case top.closer == '}' && strings.HasSuffix(entry, ":"):
+		result = input[:top.entryStart]
+	case inString:
+		result += `"`
+	}
+
+	result = strings.TrimRight(result, " \t\r\n")
+	result = strings.TrimSuffix(result, ",")
+	closers := strings.Builder{}
+	for i := len(stack) - 1; i >= 0; i-- {
+		closers.WriteByte(stack[i].closer)
+	}
+	return result + closers.String(), true, nil
+}
@@ -0,0 +1,131 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_success_closes_truncated_input_and_drops_partial_key(t *testing.T) {
+	input := `{"items": [{"id": 1}, {"id": 2, "na`
+	expected := `{
+    "items": [
+        {
+            "id": 1
+        },
+        {
+            "id": 2
+        }
+    ]
+}`
+
+	result, err := Parse(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_closes_truncated_string_value(t *testing.T) {
+	input := `{"users": [{"id": 1, "name": "some`
+	expected := `{
+    "users": [
+        {
+            "id": 1,
+            "name": "some"
+        }
+    ]
+}`
+
+	result, err := Parse(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_success_drops_key_missing_its_value(t *testing.T) {
+	input := `{"id": 1, "name":`
+	expected := `{
+    "id": 1
+}`
+
+	result, err := Parse(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_ParseResult_success_reports_truncation(t *testing.T) {
+	input := `{"id": 1, "tags": ["a", "b"`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, []Diagnostic{{
+		Kind:    DiagnosticTruncated,
+		Start:   len(input),
+		End:     len(input),
+		Message: "input was cut off, the open structures were closed and the data is incomplete",
+	}}, result.Diagnostics)
+}
+
+func TestParser_Parse_success_marks_truncated_output(t *testing.T) {
+	input := `{"id": 1, "tags": ["a", "b"`
+	expected := `{
+    "__truncated": true,
+    "id": 1,
+    "tags": [
+        "a",
+        "b"
+    ]
+}`
+
+	p := &Parser{Truncation: TruncationMark}
+	result, err := p.Parse(input)
+
+	assert.Equal(t, &TruncatedError{Offset: len(input)}, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParser_ParseResult_success_no_diagnostics_for_complete_input(t *testing.T) {
+	input := `{"id": 1, "odd": [1, tmp", 2]}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Empty(t, result.Diagnostics)
+}
+
+func TestCloseTruncated_only_closes_a_single_document(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		closed bool
+	}{
+		{"single", `{"a": [1, 2`, true},
+		{"several", "{\"a\":1}\n{\"a\":2}\n{\"a\":3, \"b", false},
+		{"trailing_text", `{"a":1} {"b": [`, false},
+		{"complete", `{"a":1}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, closed, _ := (&parseState{}).closeTruncated(tt.input)
+
+			assert.Equal(t, tt.closed, closed)
+		})
+	}
+}
+
+func TestParser_ParseResult_success_reports_only_the_truncated_document(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":2}\n{\"a\":3, \"b\": [1"
+
+	result, err := NewParser(WithCompact()).ParseResult(input)
+
+	assert.Equal(t, DocumentErrors{{Index: 2, Line: 3, Err: &TruncatedError{Offset: len(input)}}}, err)
+	assert.Equal(t, "{\"a\":1}\n\n{\"a\":2}\n\n{\"a\":3,\"b\":[1]}", result.Output)
+	assert.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, len(input), result.Diagnostics[0].Start)
+	assert.Len(t, result.Repairs, 1)
+	assert.Equal(t, RepairClosed, result.Repairs[0].Kind)
+	assert.Equal(t, len(input), result.Repairs[0].Start)
+}
@@ -0,0 +1,202 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultMaxStringDepth = 10
+
+// maxGunzipped is the most we'll decompress out of a single string, so a small string can't blow up into
+// gigabytes.
+const maxGunzipped = 16 << 20
+
+// Keys KeepOriginal puts the decoded value under, alongside the original string.
+const (
+	originalKey = "_original"
+	decodedKey  = "_decoded"
+)
+
+// StringDecodingOptions controls how we unpack JSON that's been stored in a string, like the `body` in
+// `{"body": "{\"id\": 1}"}`, which message queues and loggers love to do.  Strings escaped more than once, and
+// input that's a JSON string as a whole, are unwrapped as well.  Each string we unwrap is reported as a
+// DiagnosticDecoded, along with the steps it took.
+//
+// Strings can also be base64, gzip or URL encoded, like SQS messages or CloudWatch Logs subscription data.  We
+// don't look for those unless asked to, and either way a string is only replaced if what's inside is JSON.
+type StringDecodingOptions struct {
+	// Disabled leaves strings as they are.
+	Disabled bool
+	// MaxDepth is how many layers we'll unwrap.  Each layer of quoting, escaping or encoding counts, and so does
+	// each string inside of JSON that came out of a string.  It defaults to 10.
+	MaxDepth int
+	// Base64 decodes base64 strings, in either the standard or URL safe alphabet, with or without padding.
+	Base64 bool
+	// Gzip decompresses gzipped data found in a base64 string.
+	Gzip bool
+	// URL decodes percent encoded strings, like `%7B%22id%22%3A1%7D`.
+	URL bool
+	// KeepOriginal swaps a decoded string for an object holding both, eg. `{"_original": "eyJpZCI6MX0=",
+	// "_decoded": {"id": 1}}`.
+	KeepOriginal bool
+}
+
+func (o StringDecodingOptions) maxDepth() int {
+	if o.MaxDepth <= 0 {
+		return defaultMaxStringDepth
+	}
+	return o.MaxDepth
+}
+
+// Steps we take unwrapping a string, for the diagnostics.
+const (
+	stepUnquoted  = "unquoted"
+	stepUnescaped = "unescaped"
+	stepParsed    = "parsed"
+	stepBase64    = "base64"
+	stepGunzipped = "gunzipped"
+	stepURL       = "url"
+)
+
+// unquoteTopLevel unwraps input that's JSON stored in a string as a whole, like `"{\"a\": 1}"`, so we can parse
+// what's inside.  Anything else comes back as is.
+func (s *parseState) unquoteTopLevel(input string) string {
+	if s.StringDecoding.Disabled || !strings.HasPrefix(input, `"`) {
+		return input
+	}
+
+	text := input
+	var steps []string
+	for len(steps) < s.StringDecoding.maxDepth() && strings.HasPrefix(text, `"`) {
+		var inner string
+		if json.Unmarshal([]byte(text), &inner) != nil {
+			break
+		}
+		text = strings.TrimSpace(inner)
+		steps = append(steps, stepUnquoted)
+	}
+	if len(steps) == 0 || text == "" || !startsComplexDataStructure(rune(text[0])) {
+		return input
+	}
+	if !json.Valid([]byte(text)) && len(steps) < s.StringDecoding.maxDepth() {
+		if unescaped, ok := unescape(text); ok && json.Valid([]byte(unescaped)) {
+			text = unescaped
+			steps = append(steps, stepUnescaped)
+		}
+	}
+
+	s.report(DiagnosticDecoded, "", -1, -1, "decoded JSON stored in a string (%s)", strings.Join(steps, ", "))
+	s.stringDepth = len(steps)
+	return text
+}
+
+// unwrapString turns a string holding a JSON object or array into the data, peeling off as many layers of quoting
+// and escaping as it takes to get there.  It returns false if there isn't any JSON to be had.
+func (s *parseState) unwrapString(value, path string) (interface{}, []string, bool) {
+	if s.StringDecoding.Disabled {
+		return nil, nil, false
+	}
+
+	text := strings.TrimSpace(value)
+	var steps []string
+	for len(steps) < s.StringDecoding.maxDepth()-s.stringDepth && text != "" {
+		switch {
+		case startsComplexDataStructure(rune(text[0])):
+			// There's no telling where this sits in the input once it's been unescaped, so no offset.
+			data, err := s.decode(text, -1, path)
+			if err == nil {
+				return data, append(steps, stepParsed), true
+			}
+			unescaped, ok := unescape(text)
+			if !ok || unescaped == text {
+				return nil, nil, false
+			}
+			text = unescaped
+			steps = append(steps, stepUnescaped)
+		case text[0] == '"':
+			var inner string
+			if json.Unmarshal([]byte(text), &inner) != nil {
+				return nil, nil, false
+			}
+			text = strings.TrimSpace(inner)
+			steps = append(steps, stepUnquoted)
+		case s.StringDecoding.URL && strings.Contains(text, "%"):
+			// Only `%` is encoded in JSON, a `+` is a plus sign, not a space like in a query string.
+			decoded, err := url.PathUnescape(text)
+			if err != nil || decoded == text {
+				return nil, nil, false
+			}
+			text = strings.TrimSpace(decoded)
+			steps = append(steps, stepURL)
+		case s.StringDecoding.Base64:
+			decoded, ok := decodeBase64(text)
+			if !ok {
+				return nil, nil, false
+			}
+			steps = append(steps, stepBase64)
+			if isGzip(decoded) {
+				if !s.StringDecoding.Gzip {
+					return nil, nil, false
+				}
+				decoded, ok = gunzip(decoded)
+				if !ok {
+					return nil, nil, false
+				}
+				steps = append(steps, stepGunzipped)
+			}
+			if !utf8.Valid(decoded) {
+				return nil, nil, false
+			}
+			text = strings.TrimSpace(string(decoded))
+		default:
+			return nil, nil, false
+		}
+	}
+	return nil, nil, false
+}
+
+func decodeBase64(text string) ([]byte, bool) {
+	encodings := []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	}
+	for _, encoding := range encodings {
+		decoded, err := encoding.DecodeString(text)
+		if err == nil && len(decoded) > 0 {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+func gunzip(data []byte) ([]byte, bool) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+	result, err := io.ReadAll(io.LimitReader(reader, maxGunzipped+1))
+	if err != nil || len(result) > maxGunzipped {
+		return nil, false
+	}
+	return result, true
+}
+
+// unescape undoes one layer of escaping, eg. `{\"a\": 1}` to `{"a": 1}`.
+func unescape(text string) (string, bool) {
+	var result string
+	err := json.Unmarshal([]byte(`"`+text+`"`), &result)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(result), true
+}
@@ -0,0 +1,185 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// unwrapped skips the repair, which would otherwise unescape most strings before we get to them.
+func unwrapped(t *testing.T, p *Parser, input string) (string, []Diagnostic) {
+	s := &parseState{Parser: p, input: input}
+	data, err := s.recursiveUnmarshal(input, 0)
+	assert.Nil(t, err)
+	result, err := marshalIndent(data)
+	assert.Nil(t, err)
+	return result, s.diagnostics
+}
+
+func TestParseState_processValue_success_unwraps_strings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		steps string
+	}{
+		{"once", `{"body": "{\"id\": 1}"}`, "parsed"},
+		{"leading_whitespace", `{"body": " \n {\"id\": 1}"}`, "parsed"},
+		{"double_escaped", `{"body": "{\\\"id\\\": 1}"}`, "unescaped, parsed"},
+		{"triple_escaped", `{"body": "{\\\\\\\"id\\\\\\\": 1}"}`, "unescaped, unescaped, parsed"},
+		{"quoted", `{"body": "\"{\\\"id\\\": 1}\""}`, "unquoted, parsed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, diagnostics := unwrapped(t, &Parser{}, tt.input)
+
+			assert.JSONEq(t, `{"body": {"id": 1}}`, result)
+			assert.Equal(t, []Diagnostic{{
+				Kind:    DiagnosticDecoded,
+				Path:    "body",
+				Start:   -1,
+				End:     -1,
+				Message: "decoded JSON stored in a string (" + tt.steps + ")",
+			}}, diagnostics)
+		})
+	}
+}
+
+func TestParseState_processValue_success_unwraps_strings_inside_strings(t *testing.T) {
+	input := `{"a": "{\"b\": \"[1, 2]\"}"}`
+
+	result, diagnostics := unwrapped(t, &Parser{}, input)
+
+	assert.JSONEq(t, `{"a": {"b": [1, 2]}}`, result)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, "a", diagnostics[0].Path)
+	assert.Equal(t, "a.b", diagnostics[1].Path)
+}
+
+func TestParseState_processValue_success_stops_at_max_depth(t *testing.T) {
+	input := `{"a": "{\"b\": \"[1, 2]\"}", "c": "{\\\"d\\\": 1}"}`
+	expected := `{"a": {"b": "[1, 2]"}, "c": "{\\\"d\\\": 1}"}`
+
+	p := &Parser{StringDecoding: StringDecodingOptions{MaxDepth: 1}}
+	result, _ := unwrapped(t, p, input)
+
+	assert.JSONEq(t, expected, result)
+}
+
+func TestParseState_processValue_success_disabled(t *testing.T) {
+	input := `{"a": "{\"b\": 1}"}`
+
+	p := &Parser{StringDecoding: StringDecodingOptions{Disabled: true}}
+	result, diagnostics := unwrapped(t, p, input)
+
+	assert.JSONEq(t, input, result)
+	assert.Empty(t, diagnostics)
+}
+
+func TestParseState_processValue_success_leaves_strings_that_are_not_json(t *testing.T) {
+	input := `{"a": "{not json", "b": "\"quoted\"", "c": "[1, 2"}`
+
+	result, diagnostics := unwrapped(t, &Parser{}, input)
+
+	assert.JSONEq(t, input, result)
+	assert.Empty(t, diagnostics)
+}
+
+func TestParser_ParseResult_success_unwraps_top_level_string(t *testing.T) {
+	input := `"\"{\\\"a\\\": 1}\""`
+	expected := `{
+    "a": 1
+}`
+
+	p := &Parser{}
+	result, err := p.ParseResult(input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.Output)
+	assert.Equal(t, []Diagnostic{{
+		Kind:    DiagnosticDecoded,
+		Start:   -1,
+		End:     -1,
+		Message: "decoded JSON stored in a string (unquoted, unquoted)",
+	}}, result.Diagnostics)
+}
+
+func TestParseState_processValue_success_decodes_encoded_strings(t *testing.T) {
+	gzipped := gzipBase64(`{"id": 1}`)
+
+	tests := []struct {
+		name  string
+		value string
+		steps string
+	}{
+		{"base64", "eyJpZCI6IDF9", "base64, parsed"},
+		{"base64_url_safe_unpadded", base64.RawURLEncoding.EncodeToString([]byte(`{"id": 1}`)), "base64, parsed"},
+		{"base64_gzip", gzipped, "base64, gunzipped, parsed"},
+		{"url", "%7B%22id%22%3A%201%7D", "url, parsed"},
+		{"base64_escaped", base64.StdEncoding.EncodeToString([]byte(`"{\"id\": 1}"`)), "base64, unquoted, parsed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `{"body": "` + tt.value + `"}`
+
+			p := &Parser{StringDecoding: StringDecodingOptions{Base64: true, Gzip: true, URL: true}}
+			result, diagnostics := unwrapped(t, p, input)
+
+			assert.JSONEq(t, `{"body": {"id": 1}}`, result)
+			assert.Len(t, diagnostics, 1)
+			assert.Equal(t, "decoded JSON stored in a string ("+tt.steps+")", diagnostics[0].Message)
+		})
+	}
+}
+
+func TestParseState_processValue_success_url_keeps_plus_signs(t *testing.T) {
+	input := `{"body": "%7B%22query%22%3A%22a+b%22%7D"}`
+
+	p := &Parser{StringDecoding: StringDecodingOptions{URL: true}}
+	result, _ := unwrapped(t, p, input)
+
+	assert.JSONEq(t, `{"body": {"query": "a+b"}}`, result)
+}
+
+func TestParseState_processValue_success_encoded_strings_are_opt_in(t *testing.T) {
+	gzipped := gzipBase64(`{"id": 1}`)
+	input := `{"a": "eyJpZCI6IDF9", "b": "%7B%22id%22%3A1%7D", "c": "` + gzipped + `"}`
+
+	result, diagnostics := unwrapped(t, &Parser{}, input)
+	assert.JSONEq(t, input, result)
+	assert.Empty(t, diagnostics)
+
+	// Gzip has to be asked for on top of base64.
+	p := &Parser{StringDecoding: StringDecodingOptions{Base64: true}}
+	result, _ = unwrapped(t, p, input)
+	assert.JSONEq(t, `{"a": {"id": 1}, "b": "%7B%22id%22%3A1%7D", "c": "`+gzipped+`"}`, result)
+}
+
+func TestParseState_processValue_success_leaves_encoded_strings_that_are_not_json(t *testing.T) {
+	input := `{"word": "test", "id": "dGVzdA==", "path": "a%20b", "hash": "d41d8cd98f00b204e9800998ecf8427e"}`
+
+	p := &Parser{StringDecoding: StringDecodingOptions{Base64: true, Gzip: true, URL: true}}
+	result, diagnostics := unwrapped(t, p, input)
+
+	assert.JSONEq(t, input, result)
+	assert.Empty(t, diagnostics)
+}
+
+func TestParseState_processValue_success_keeps_original(t *testing.T) {
+	input := `{"body": "eyJpZCI6IDF9"}`
+	expected := `{"body": {"_original": "eyJpZCI6IDF9", "_decoded": {"id": 1}}}`
+
+	p := &Parser{StringDecoding: StringDecodingOptions{Base64: true, KeepOriginal: true}}
+	result, _ := unwrapped(t, p, input)
+
+	assert.JSONEq(t, expected, result)
+}
+
+func gzipBase64(text string) string {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write([]byte(text))
+	_ = writer.Close()
+	return base64.StdEncoding.EncodeToString(compressed.Bytes())
+}
@@ -1,35 +1,70 @@
 package parse
 
 import (
-	"fmt"
-	"regexp"
+	"strconv"
 	"strings"
 )
 
+// These get called for every character we repair, so they're switches rather than `strings.ContainsRune`.
 func startsComplexDataStructure(value rune) bool {
-	return strings.ContainsRune("{[", value)
+	return value == '{' || value == '['
 }
 
 func endsComplexDataStructure(value rune) bool {
-	return strings.ContainsRune("}]", value)
+	return value == '}' || value == ']'
 }
 
 func isSkippableCharacter(value rune) bool {
-	switch {
-	case strings.ContainsRune(`"' `, value):
+	switch value {
+	case '"', '\'', ' ', '\t':
 		return true
-	//case '\n' == value:
-	//	return true
-	case '\t' == value:
+	}
+	return false
+}
+
+func isWhitespace(value rune) bool {
+	switch value {
+	case ' ', '\t', '\r', '\n':
 		return true
 	}
-	return strings.ContainsRune(`"'`, value)
+	return false
 }
 
+// IsNumber reports whether the value is an integer or floating-point number, like `-?\d+(\.\d+)?([eE][+-]?\d+)?`.
+// We check every value we repair with it, so it's written out by hand rather than as a regex.
 func IsNumber(value string) bool {
-	// A regex to match integers or floating-point numbers
-	reNumber := regexp.MustCompile(`^-?\d+(\.\d+)?([eE][+-]?\d+)?$`)
-	return reNumber.MatchString(value)
+	i := 0
+	if i < len(value) && value[i] == '-' {
+		i++
+	}
+	i, ok := skipDigits(value, i)
+	if !ok {
+		return false
+	}
+	if i < len(value) && value[i] == '.' {
+		if i, ok = skipDigits(value, i+1); !ok {
+			return false
+		}
+	}
+	if i < len(value) && (value[i] == 'e' || value[i] == 'E') {
+		i++
+		if i < len(value) && (value[i] == '+' || value[i] == '-') {
+			i++
+		}
+		if i, ok = skipDigits(value, i); !ok {
+			return false
+		}
+	}
+	return i == len(value)
+}
+
+// skipDigits returns the index after the run of digits starting at i, and whether there were any.
+func skipDigits(value string, i int) (int, bool) {
+	start := i
+	for i < len(value) && value[i] >= '0' && value[i] <= '9' {
+		i++
+	}
+	return i, i > start
 }
 
 func IsComplexObject(value string) bool {
@@ -45,27 +80,73 @@ func containsRune(runes []rune, r rune) bool {
 	return false
 }
 
-func writeLine(key, value, processedData strings.Builder) strings.Builder {
+// joinPath builds up the path we report diagnostics against, eg. `a.b[0].c`.
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// isContainer reports whether the value is an object or an array, the only things with anything inside to look at.
+func isContainer(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+func indexPath(parent string, index int) string {
+	return parent + "[" + strconv.Itoa(index) + "]"
+}
+
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// trimEndQuotes strips a quote off each end of the value, along with any `\` in front of it.
+func trimEndQuotes(value string) string {
+	if trimmed := strings.TrimLeft(value, `\`); trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+		value = trimmed[1:]
+	}
+	if n := len(value); n > 0 && (value[n-1] == '"' || value[n-1] == '\'') {
+		value = strings.TrimRight(value[:n-1], `\`)
+	}
+	return value
+}
+
+// writeLine writes the key and value out to the processed data, and resets them for the next pair.
+func writeLine(key, value, processedData *strings.Builder) {
 	if key.Len() > 0 {
-		processedData.WriteString(fmt.Sprintf("%s: %s,", key.String(), value.String()))
-	} else {
-		processedData.WriteString(fmt.Sprintf("%s,", value.String()))
+		processedData.WriteString(key.String())
+		processedData.WriteString(": ")
 	}
-	return processedData
+	processedData.WriteString(value.String())
+	processedData.WriteByte(',')
+	key.Reset()
+	value.Reset()
 }
 
-func findNextAnchorCharacter(data []rune) rune {
+// findNextAnchorCharacter finds the next `:`, `,` or newline, and whether there's any text before it, up until
+// the end of the object or array we're in.
+func findNextAnchorCharacter(data []rune) (anchor rune, textBefore bool) {
+	closed := false
 	for _, r := range data {
-		switch r {
-		case ':':
-			return r
-		case ',':
-			return r
-		case '\n':
-			return r
+		switch {
+		case r == ':' || r == ',' || r == '\n':
+			return r, textBefore
+		case endsComplexDataStructure(r):
+			closed = true
+		case !closed && !isWhitespace(r) && !isSkippableCharacter(r):
+			textBefore = true
 		}
 	}
-	return 0
+	return 0, textBefore
 }
 
 //func stripUnneededQuotes(input interface{}) interface{} {
@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTrimEndQuotes(t *testing.T) {
+	// What we used to strip them with.
+	pattern := regexp.MustCompile(`^\\*"|\\*"$|^\\*'|\\*'$`)
+	inputs := []string{
+		``, `"`, `'`, `""`, `''`, `"'`, `'"`, `\"`, `\\"a\\"`, `"a"`, `'a'`, `a"`, `"a`, `a\"`, `"\`, `\`, `\a\`,
+		`"\"`, `\'x`, `x\\'`, `"a b"`, `a`, `"{"a": 1}"`,
+	}
+	for _, input := range inputs {
+		assert.Equal(t, pattern.ReplaceAllString(input, ""), trimEndQuotes(input), input)
+	}
+}
+
+func TestIsNumber(t *testing.T) {
+	// What we used to match them with.
+	pattern := regexp.MustCompile(`^-?\d+(\.\d+)?([eE][+-]?\d+)?$`)
+	inputs := []string{
+		``, `-`, `0`, `-0`, `007`, `42`, `-42`, `1.5`, `1.`, `.5`, `1.5e10`, `1e+10`, `1E-10`, `1e`, `1e+`, `1.e5`,
+		`--1`, `+1`, `1 `, ` 1`, `1.5.5`, `1e5e5`, `0x10`, `١٢`, `12a`, `NaN`,
+	}
+	for _, input := range inputs {
+		assert.Equal(t, pattern.MatchString(input), IsNumber(input), input)
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	key, value, processedData := strings.Builder{}, strings.Builder{}, strings.Builder{}
+	key.WriteString(`"a"`)
+	value.WriteString(`1`)
+	writeLine(&key, &value, &processedData)
+	value.WriteString(`2`)
+	writeLine(&key, &value, &processedData)
+
+	assert.Equal(t, `"a": 1,2,`, processedData.String())
+	assert.Zero(t, key.Len())
+	assert.Zero(t, value.Len())
+}
+
+func TestFindNextAnchorCharacter(t *testing.T) {
+	tests := []struct {
+		input      string
+		anchor     rune
+		textBefore bool
+	}{
+		{"b: 1", ':', true},
+		{"  : 1", ':', false},
+		{"world\nb: 1", '\n', true},
+		{`"" , x`, ',', false},
+		{"} more: 1", ':', false},
+		{"end", 0, true},
+	}
+	for _, tt := range tests {
+		anchor, textBefore := findNextAnchorCharacter([]rune(tt.input))
+
+		assert.Equal(t, tt.anchor, anchor, tt.input)
+		assert.Equal(t, tt.textBefore, textBefore, tt.input)
+	}
+}
@@ -0,0 +1,435 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxValidationDepth is as deep as we'll follow nested objects and arrays when validating.
+const maxValidationDepth = 10000
+
+// StrictOptions controls strict mode, where we only accept input that's valid JSON as RFC 8259 has it, and refuse
+// to repair anything.  The optional checks are for things RFC 8259 allows, but leaves up to whoever reads the JSON.
+type StrictOptions struct {
+	// Enabled makes the Parser validate the input, and fail with ValidationErrors rather than repairing it.  Valid
+	// input is formatted as is, without expanding dot notation keys or unpacking strings.
+	Enabled bool
+	// DuplicateKeys flags a key that shows up more than once in the same object.
+	DuplicateKeys bool
+	// LoneSurrogates flags `\u` escapes of half a UTF-16 surrogate pair, which can't be turned into a character.
+	LoneSurrogates bool
+	// NumberRange flags numbers too big for a float64, and integers too big for an int64.
+	NumberRange bool
+}
+
+// ValidationKind is the kind of problem validation found.
+type ValidationKind string
+
+const (
+	// ValidationSyntax is input that isn't JSON.  Validation stops at the first one.
+	ValidationSyntax ValidationKind = "syntax"
+	// ValidationDuplicateKey is a key that shows up more than once in the same object.
+	ValidationDuplicateKey ValidationKind = "duplicate_key"
+	// ValidationLoneSurrogate is an escape of half a UTF-16 surrogate pair.
+	ValidationLoneSurrogate ValidationKind = "lone_surrogate"
+	// ValidationNumberRange is a number that doesn't fit in a float64, or an integer that doesn't fit in an int64.
+	ValidationNumberRange ValidationKind = "number_range"
+)
+
+// ValidationError is a single problem with the input, and exactly where it is.
+type ValidationError struct {
+	Kind ValidationKind
+	// Offset is the byte offset into the input.  Line and Column start at 1, and Column counts characters.
+	Offset int
+	Line   int
+	Column int
+	// Path is where in the data the problem is, eg. `a.b[0]`, as far as we got.
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors is every problem validation found, in the order they're in the input.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// parseStrict validates the input, and formats it as is if it's valid.
+func (s *parseState) parseStrict() (string, error) {
+	err := Validate(s.input, s.Strict)
+	if err != nil {
+		return "", err
+	}
+	data, err := s.decode(s.input, 0, "")
+	if err != nil {
+		return "", err
+	}
+	return s.marshal(data)
+}
+
+// Validate checks the input is valid JSON as RFC 8259 has it, and returns ValidationErrors if it isn't.  Enabled
+// is ignored, the optional checks are made if they're asked for.
+func Validate(input string, options StrictOptions) (err error) {
+	defer recoverPanic(&err)
+	v := &validator{input: input, options: options, lines: NewLineCounter(input)}
+	v.validate()
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// validator walks the input byte by byte, the same as the grammar in RFC 8259.
+type validator struct {
+	input   string
+	options StrictOptions
+	pos     int
+	errs    ValidationErrors
+	// lines works out the line and column of each problem we record.
+	lines *LineCounter
+}
+
+// errSyntax stops validation, once the syntax error itself has been recorded.
+var errSyntax = errors.New("syntax error")
+
+func (v *validator) validate() {
+	if strings.HasPrefix(v.input, "\uFEFF") {
+		v.syntax(0, "", "the input starts with a byte order mark")
+		return
+	}
+	v.skipWhitespace()
+	if v.value("", 0) != nil {
+		return
+	}
+	v.skipWhitespace()
+	if v.pos < len(v.input) {
+		v.syntax(v.pos, "", "%s after the top level value", v.describe())
+	}
+}
+
+// fail records a problem at the offset.
+func (v *validator) fail(kind ValidationKind, offset int, path, format string, args ...interface{}) {
+	line, column := v.lines.Position(offset)
+	v.errs = append(v.errs, &ValidationError{
+		Kind:    kind,
+		Offset:  offset,
+		Line:    line,
+		Column:  column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) syntax(offset int, path, format string, args ...interface{}) error {
+	v.fail(ValidationSyntax, offset, path, format, args...)
+	return errSyntax
+}
+
+// describe is what's at the current position, for error messages.
+func (v *validator) describe() string {
+	if v.pos >= len(v.input) {
+		return "the end of the input"
+	}
+	r, _ := utf8.DecodeRuneInString(v.input[v.pos:])
+	if r == utf8.RuneError {
+		return fmt.Sprintf("byte %#x", v.input[v.pos])
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+func (v *validator) skipWhitespace() {
+	for v.pos < len(v.input) && strings.IndexByte(" \t\r\n", v.input[v.pos]) >= 0 {
+		v.pos++
+	}
+}
+
+func (v *validator) value(path string, depth int) error {
+	if depth > maxValidationDepth {
+		return v.syntax(v.pos, path, "nested more than %d levels deep", maxValidationDepth)
+	}
+	if v.pos >= len(v.input) {
+		return v.syntax(v.pos, path, "expected a value, found the end of the input")
+	}
+	switch c := v.input[v.pos]; {
+	case c == '{':
+		return v.object(path, depth)
+	case c == '[':
+		return v.array(path, depth)
+	case c == '"':
+		_, err := v.string(path)
+		return err
+	case c == '-' || (c >= '0' && c <= '9'):
+		return v.number(path)
+	default:
+		for _, literal := range []string{"true", "false", "null"} {
+			if strings.HasPrefix(v.input[v.pos:], literal) {
+				v.pos += len(literal)
+				return nil
+			}
+		}
+		return v.syntax(v.pos, path, "expected a value, found %s", v.describe())
+	}
+}
+
+func (v *validator) object(path string, depth int) error {
+	v.pos++
+	v.skipWhitespace()
+	if v.pos < len(v.input) && v.input[v.pos] == '}' {
+		v.pos++
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for {
+		v.skipWhitespace()
+		if v.pos >= len(v.input) || v.input[v.pos] != '"' {
+			return v.syntax(v.pos, path, "expected a key in double quotes, found %s", v.describe())
+		}
+		start := v.pos
+		key, err := v.string(path)
+		if err != nil {
+			return err
+		}
+		keyPath := joinPath(path, key)
+		if v.options.DuplicateKeys && seen[key] {
+			v.fail(ValidationDuplicateKey, start, keyPath, "duplicate key %q", key)
+		}
+		seen[key] = true
+
+		v.skipWhitespace()
+		if v.pos >= len(v.input) || v.input[v.pos] != ':' {
+			return v.syntax(v.pos, keyPath, "expected ':' after the key, found %s", v.describe())
+		}
+		v.pos++
+		v.skipWhitespace()
+		err = v.value(keyPath, depth+1)
+		if err != nil {
+			return err
+		}
+
+		v.skipWhitespace()
+		if v.pos < len(v.input) && v.input[v.pos] == ',' {
+			comma := v.pos
+			v.pos++
+			v.skipWhitespace()
+			if v.pos < len(v.input) && v.input[v.pos] == '}' {
+				return v.syntax(comma, keyPath, "trailing comma before '}'")
+			}
+			continue
+		}
+		if v.pos < len(v.input) && v.input[v.pos] == '}' {
+			v.pos++
+			return nil
+		}
+		return v.syntax(v.pos, keyPath, "expected ',' or '}' after the value, found %s", v.describe())
+	}
+}
+
+func (v *validator) array(path string, depth int) error {
+	v.pos++
+	v.skipWhitespace()
+	if v.pos < len(v.input) && v.input[v.pos] == ']' {
+		v.pos++
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		v.skipWhitespace()
+		err := v.value(indexPath(path, i), depth+1)
+		if err != nil {
+			return err
+		}
+
+		v.skipWhitespace()
+		if v.pos < len(v.input) && v.input[v.pos] == ',' {
+			comma := v.pos
+			v.pos++
+			v.skipWhitespace()
+			if v.pos < len(v.input) && v.input[v.pos] == ']' {
+				return v.syntax(comma, indexPath(path, i), "trailing comma before ']'")
+			}
+			continue
+		}
+		if v.pos < len(v.input) && v.input[v.pos] == ']' {
+			v.pos++
+			return nil
+		}
+		return v.syntax(v.pos, indexPath(path, i), "expected ',' or ']' after the value, found %s", v.describe())
+	}
+}
+
+// string validates a string, and returns what it says, as far as we need it for finding duplicate keys.
+func (v *validator) string(path string) (string, error) {
+	start := v.pos
+	v.pos++
+	result := strings.Builder{}
+	for {
+		if v.pos >= len(v.input) {
+			return "", v.syntax(start, path, "string is never closed")
+		}
+		c := v.input[v.pos]
+		switch {
+		case c == '"':
+			v.pos++
+			return result.String(), nil
+		case c < 0x20:
+			return "", v.syntax(v.pos, path, "control character %#x in a string has to be escaped", c)
+		case c == '\\':
+			err := v.escape(path, &result)
+			if err != nil {
+				return "", err
+			}
+		case c < utf8.RuneSelf:
+			result.WriteByte(c)
+			v.pos++
+		default:
+			r, size := utf8.DecodeRuneInString(v.input[v.pos:])
+			if r == utf8.RuneError && size <= 1 {
+				return "", v.syntax(v.pos, path, "invalid UTF-8 byte %#x", c)
+			}
+			result.WriteRune(r)
+			v.pos += size
+		}
+	}
+}
+
+func (v *validator) escape(path string, result *strings.Builder) error {
+	start := v.pos
+	v.pos++
+	if v.pos >= len(v.input) {
+		return v.syntax(start, path, "string is never closed")
+	}
+	c := v.input[v.pos]
+	v.pos++
+	switch c {
+	case '"', '\\', '/':
+		result.WriteByte(c)
+	case 'b':
+		result.WriteByte('\b')
+	case 'f':
+		result.WriteByte('\f')
+	case 'n':
+		result.WriteByte('\n')
+	case 'r':
+		result.WriteByte('\r')
+	case 't':
+		result.WriteByte('\t')
+	case 'u':
+		r, err := v.hex(start, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case r >= 0xD800 && r < 0xDC00:
+			// The first half of a pair, which needs the second half right after it.
+			if strings.HasPrefix(v.input[v.pos:], `\u`) {
+				save := v.pos
+				v.pos += 2
+				low, err := v.hex(save, path)
+				if err != nil {
+					return err
+				}
+				if low >= 0xDC00 && low < 0xE000 {
+					result.WriteRune((r-0xD800)<<10 + (low - 0xDC00) + 0x10000)
+					return nil
+				}
+				v.pos = save
+			}
+			v.loneSurrogate(start, path, r)
+		case r >= 0xDC00 && r < 0xE000:
+			v.loneSurrogate(start, path, r)
+		default:
+			result.WriteRune(r)
+		}
+	default:
+		return v.syntax(start, path, "invalid escape %q", "\\"+string(rune(c)))
+	}
+	return nil
+}
+
+// hex reads the four hex digits of a `\u` escape, which starts at the offset.
+func (v *validator) hex(start int, path string) (rune, error) {
+	if v.pos+4 > len(v.input) {
+		return 0, v.syntax(start, path, "\\u escape needs four hex digits")
+	}
+	value, err := strconv.ParseUint(v.input[v.pos:v.pos+4], 16, 32)
+	if err != nil {
+		return 0, v.syntax(start, path, "\\u escape needs four hex digits, found %q", v.input[v.pos:v.pos+4])
+	}
+	v.pos += 4
+	return rune(value), nil
+}
+
+func (v *validator) loneSurrogate(offset int, path string, r rune) {
+	if v.options.LoneSurrogates {
+		v.fail(ValidationLoneSurrogate, offset, path, "\\u%04X is half of a surrogate pair, on its own", r)
+	}
+}
+
+func (v *validator) number(path string) error {
+	start := v.pos
+	if v.input[v.pos] == '-' {
+		v.pos++
+	}
+	digits := func() int {
+		count := 0
+		for v.pos < len(v.input) && v.input[v.pos] >= '0' && v.input[v.pos] <= '9' {
+			v.pos++
+			count++
+		}
+		return count
+	}
+
+	integer := true
+	switch {
+	case v.pos < len(v.input) && v.input[v.pos] == '0':
+		v.pos++
+		if v.pos < len(v.input) && v.input[v.pos] >= '0' && v.input[v.pos] <= '9' {
+			return v.syntax(start, path, "numbers can't have leading zeros")
+		}
+	case digits() == 0:
+		return v.syntax(v.pos, path, "expected a digit, found %s", v.describe())
+	}
+	if v.pos < len(v.input) && v.input[v.pos] == '.' {
+		integer = false
+		v.pos++
+		if digits() == 0 {
+			return v.syntax(v.pos, path, "expected a digit after the decimal point, found %s", v.describe())
+		}
+	}
+	if v.pos < len(v.input) && (v.input[v.pos] == 'e' || v.input[v.pos] == 'E') {
+		integer = false
+		v.pos++
+		if v.pos < len(v.input) && (v.input[v.pos] == '+' || v.input[v.pos] == '-') {
+			v.pos++
+		}
+		if digits() == 0 {
+			return v.syntax(v.pos, path, "expected a digit in the exponent, found %s", v.describe())
+		}
+	}
+
+	if v.options.NumberRange {
+		text := v.input[start:v.pos]
+		if integer {
+			if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+				v.fail(ValidationNumberRange, start, path, "%s doesn't fit in an int64", text)
+			}
+		} else if value, err := strconv.ParseFloat(text, 64); err != nil || math.IsInf(value, 0) {
+			v.fail(ValidationNumberRange, start, path, "%s doesn't fit in a float64", text)
+		}
+	}
+	return nil
+}
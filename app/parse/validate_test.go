@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidate_success(t *testing.T) {
+	inputs := []string{
+		`{}`,
+		`[]`,
+		` {"a": [1, -0.5, 1e10, 2E-3, true, false, null, "x\né😀"]} `,
+		`"just a string"`,
+		`42`,
+		"{\"é\": {\"nested\": [[]]}}",
+	}
+	for _, input := range inputs {
+		err := Validate(input, StrictOptions{DuplicateKeys: true, LoneSurrogates: true, NumberRange: true})
+
+		assert.Nil(t, err, input)
+		assert.True(t, json.Valid([]byte(input)), input)
+	}
+}
+
+func TestValidate_failure_syntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		line    int
+		column  int
+		path    string
+		message string
+	}{
+		{"empty", ``, 1, 1, "", "expected a value, found the end of the input"},
+		{"single_quotes", `{'a': 1}`, 1, 2, "", `expected a key in double quotes, found '\''`},
+		{"bare_key", "{\n  a: 1}", 2, 3, "", `expected a key in double quotes, found 'a'`},
+		{"trailing_comma", "{\"a\": [1, 2,\n]}", 1, 12, "a[1]", "trailing comma before ']'"},
+		{"missing_comma", `{"a": 1 "b": 2}`, 1, 9, "a", `expected ',' or '}' after the value, found '"'`},
+		{"leading_zero", `{"zip": 02134}`, 1, 9, "zip", "numbers can't have leading zeros"},
+		{"python", `[None]`, 1, 2, "[0]", `expected a value, found 'N'`},
+		{"unclosed_string", `["abc`, 1, 2, "[0]", "string is never closed"},
+		{"control_character", "[\"a\tb\"]", 1, 4, "[0]", "control character 0x9 in a string has to be escaped"},
+		{"bad_escape", `["\x"]`, 1, 3, "[0]", `invalid escape "\\x"`},
+		{"trailing_data", `{} {}`, 1, 4, "", `'{' after the top level value`},
+		{"unicode_column", "{\"é\": tru}", 1, 7, "é", `expected a value, found 't'`},
+		{"byte_order_mark", "\uFEFF{}", 1, 1, "", "the input starts with a byte order mark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.input, StrictOptions{})
+
+			var errs ValidationErrors
+			assert.True(t, errors.As(err, &errs))
+			assert.Len(t, errs, 1)
+			assert.Equal(t, ValidationSyntax, errs[0].Kind)
+			assert.Equal(t, tt.line, errs[0].Line)
+			assert.Equal(t, tt.column, errs[0].Column)
+			assert.Equal(t, tt.path, errs[0].Path)
+			assert.Equal(t, tt.message, errs[0].Message)
+		})
+	}
+}
+
+func TestValidate_failure_optional_checks(t *testing.T) {
+	input := `{"a": 1, "b": "\ud800", "a": 2, "c": [9223372036854775808, 1e400, 1.5, "\udc00😀"]}`
+
+	err := Validate(input, StrictOptions{DuplicateKeys: true, LoneSurrogates: true, NumberRange: true})
+
+	var errs ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []*ValidationError{
+		{Kind: ValidationLoneSurrogate, Offset: 15, Line: 1, Column: 16, Path: "b",
+			Message: `\uD800 is half of a surrogate pair, on its own`},
+		{Kind: ValidationDuplicateKey, Offset: 24, Line: 1, Column: 25, Path: "a", Message: `duplicate key "a"`},
+		{Kind: ValidationNumberRange, Offset: 38, Line: 1, Column: 39, Path: "c[0]",
+			Message: "9223372036854775808 doesn't fit in an int64"},
+		{Kind: ValidationNumberRange, Offset: 59, Line: 1, Column: 60, Path: "c[1]",
+			Message: "1e400 doesn't fit in a float64"},
+		{Kind: ValidationLoneSurrogate, Offset: 72, Line: 1, Column: 73, Path: "c[3]",
+			Message: `\uDC00 is half of a surrogate pair, on its own`},
+	}, []*ValidationError(errs))
+
+	assert.Nil(t, Validate(input, StrictOptions{}))
+}
+
+func TestParse_success_strict(t *testing.T) {
+	input := `{"b.c": "{\"x\": 1}", "a": 1.50}`
+	expected := `{
+    "a": 1.50,
+    "b.c": "{\"x\": 1}"
+}`
+
+	result, err := Parse(input, WithStrict(StrictOptions{}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestParse_failure_strict_refuses_to_repair(t *testing.T) {
+	result, err := Parse("{a: 1,\n 'b': 2}", WithStrict(StrictOptions{}))
+
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, `line 1, column 2: expected a key in double quotes, found 'a'`)
+}
+
+func TestValidate_failure_positions_across_lines(t *testing.T) {
+	input := "{\n  \"é\": 1,\n  \"é\": 2,\n  \"b\": [1e400,\n    \"x\", 1e400]\n}"
+
+	err := Validate(input, StrictOptions{DuplicateKeys: true, NumberRange: true})
+
+	var errs ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	positions := make([][2]int, len(errs))
+	for i, e := range errs {
+		positions[i] = [2]int{e.Line, e.Column}
+	}
+	assert.Equal(t, [][2]int{{3, 3}, {4, 9}, {5, 10}}, positions)
+}